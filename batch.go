@@ -0,0 +1,37 @@
+package sandbox
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// ExecRequest 是批量执行中的一项
+type ExecRequest struct {
+	Code     string
+	Language string
+	Timeout  time.Duration // 为 0 时使用执行器的默认超时
+}
+
+// ExecuteBatch 并发执行一组代码，返回的结果与 reqs 顺序一一对应
+func (e *CodeExecutor) ExecuteBatch(reqs []ExecRequest) []ExecutionResult {
+	return e.ExecuteBatchContext(context.Background(), reqs)
+}
+
+// ExecuteBatchContext 在 ctx 的控制下并发执行一组代码，并发度受工作池 maxWorkers 约束，
+// 各项独立完成，慢的任务不会阻塞快的任务。取消 ctx 会终止整批中尚未结束的执行
+func (e *CodeExecutor) ExecuteBatchContext(ctx context.Context, reqs []ExecRequest) []ExecutionResult {
+	results := make([]ExecutionResult, len(reqs))
+
+	var wg sync.WaitGroup
+	wg.Add(len(reqs))
+	for i, req := range reqs {
+		go func(i int, req ExecRequest) {
+			defer wg.Done()
+			results[i] = e.execute(ctx, req.Code, req.Language, ExecOptions{Timeout: req.Timeout})
+		}(i, req)
+	}
+	wg.Wait()
+
+	return results
+}
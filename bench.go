@@ -0,0 +1,56 @@
+package sandbox
+
+import "context"
+
+// BenchResult 是 ExecuteBench 的统计结果，耗时均取自各次执行的 DurationMs
+type BenchResult struct {
+	Runs        int     `json:"runs"`         // 成功完成的执行次数
+	DurationsMs []int64 `json:"durations_ms"` // 成功执行的耗时，按执行顺序
+	MinMs       int64   `json:"min_ms"`
+	AvgMs       float64 `json:"avg_ms"`
+	MaxMs       int64   `json:"max_ms"`
+
+	// Last 是最后一次执行的结果：全部成功时为最后一次成功的执行，提前结束时为失败的那次
+	Last ExecutionResult `json:"last"`
+	// Aborted 为 true 表示某次执行失败或超时，其余执行不再进行，统计只包含此前成功的执行
+	Aborted bool `json:"aborted"`
+}
+
+// ExecuteBench 把同一段代码顺序执行 runs 次并统计耗时，见 ExecuteBenchContext
+func (e *CodeExecutor) ExecuteBench(code string, language string, runs int) BenchResult {
+	return e.ExecuteBenchContext(context.Background(), code, language, runs)
+}
+
+// ExecuteBenchContext 在 ctx 的控制下把同一段代码顺序执行 runs 次（小于 1 时按 1 处理），
+// 统计最短、平均与最长耗时。每次执行都经过工作池排队且不使用结果缓存；
+// 某次执行失败、超时或 ctx 被取消时立即停止，返回已完成部分的统计
+func (e *CodeExecutor) ExecuteBenchContext(ctx context.Context, code string, language string, runs int) BenchResult {
+	if runs < 1 {
+		runs = 1
+	}
+
+	var bench BenchResult
+	var total int64
+	for i := 0; i < runs; i++ {
+		result := e.execute(ctx, code, language, ExecOptions{noCache: true})
+		bench.Last = result
+		if !result.Success {
+			bench.Aborted = true
+			break
+		}
+
+		bench.DurationsMs = append(bench.DurationsMs, result.DurationMs)
+		if bench.Runs == 0 || result.DurationMs < bench.MinMs {
+			bench.MinMs = result.DurationMs
+		}
+		if result.DurationMs > bench.MaxMs {
+			bench.MaxMs = result.DurationMs
+		}
+		total += result.DurationMs
+		bench.Runs++
+	}
+	if bench.Runs > 0 {
+		bench.AvgMs = float64(total) / float64(bench.Runs)
+	}
+	return bench
+}
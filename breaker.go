@@ -0,0 +1,136 @@
+package sandbox
+
+import (
+	"sync"
+	"time"
+)
+
+// 熔断器的默认参数
+const (
+	defaultBreakerThreshold = 5
+	defaultBreakerCooldown  = 30 * time.Second
+)
+
+// CircuitBreakerConfig 配置按语言的熔断：某个语言连续 FailureThreshold 次因沙箱自身的故障
+// （ErrorKindInternal，如解释器无法启动、磁盘已满）失败后，在 Cooldown 内直接以 ErrRuntimeUnavailable
+// 拒绝该语言的执行而不再尝试启动进程；冷却结束后放行一次执行作为探测，成功则恢复，失败则继续熔断
+type CircuitBreakerConfig struct {
+	FailureThreshold int           // 触发熔断的连续失败次数，小于等于 0 时使用 5
+	Cooldown         time.Duration // 熔断后到下一次探测的时间，小于等于 0 时使用 30 秒
+}
+
+// CircuitState 是一个语言的熔断器状态
+type CircuitState string
+
+const (
+	CircuitClosed   CircuitState = "closed"    // 正常执行
+	CircuitOpen     CircuitState = "open"      // 熔断中，执行被直接拒绝
+	CircuitHalfOpen CircuitState = "half_open" // 冷却结束，正在放行一次探测执行
+)
+
+// circuitBreaker 是单个语言的熔断器
+type circuitBreaker struct {
+	state    CircuitState
+	failures int       // 连续失败次数
+	openedAt time.Time // 最近一次进入熔断的时间
+	probing  bool      // 半开状态下已有一次探测执行在进行
+}
+
+// breakers 管理各语言的熔断器，config 为 nil 时不熔断
+type breakers struct {
+	config *CircuitBreakerConfig
+
+	mu       sync.Mutex
+	breakers map[string]*circuitBreaker
+}
+
+// newBreakers 按 config 创建熔断器集合，config 为 nil 时返回不熔断的集合
+func newBreakers(config *CircuitBreakerConfig) *breakers {
+	b := &breakers{breakers: make(map[string]*circuitBreaker)}
+	if config != nil {
+		c := *config
+		if c.FailureThreshold <= 0 {
+			c.FailureThreshold = defaultBreakerThreshold
+		}
+		if c.Cooldown <= 0 {
+			c.Cooldown = defaultBreakerCooldown
+		}
+		b.config = &c
+	}
+	return b
+}
+
+// allow 判断此刻能否执行 language 的代码，不能时返回熔断器记录的连续失败次数
+func (b *breakers) allow(language string) (ok bool, failures int) {
+	if b.config == nil {
+		return true, 0
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	cb, exists := b.breakers[language]
+	if !exists {
+		return true, 0
+	}
+	switch cb.state {
+	case CircuitOpen:
+		if time.Since(cb.openedAt) < b.config.Cooldown {
+			return false, cb.failures
+		}
+		cb.state, cb.probing = CircuitHalfOpen, true
+	case CircuitHalfOpen:
+		if cb.probing {
+			return false, cb.failures
+		}
+		cb.probing = true
+	}
+	return true, 0
+}
+
+// record 根据 language 一次执行的结果更新熔断器。内部错误计为失败，代码实际运行过的结果计为成功，
+// 执行前就被拒绝的结果（参数非法、排队超时等）不影响计数，但会释放半开状态下的探测名额
+func (b *breakers) record(language string, result ExecutionResult) {
+	if b.config == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	cb, exists := b.breakers[language]
+	if result.ErrorKind == ErrorKindInternal {
+		// 只为发生过失败的语言创建熔断器，任意的未知语言名不会占用内存
+		if !exists {
+			cb = &circuitBreaker{state: CircuitClosed}
+			b.breakers[language] = cb
+		}
+		cb.failures++
+		if cb.state == CircuitHalfOpen || cb.failures >= b.config.FailureThreshold {
+			cb.state, cb.openedAt = CircuitOpen, time.Now()
+		}
+		cb.probing = false
+		return
+	}
+	if !exists {
+		return
+	}
+	if phaseOf(result) != PhaseSetup {
+		cb.state, cb.failures = CircuitClosed, 0
+	}
+	cb.probing = false
+}
+
+// state 返回 language 的熔断器状态
+func (b *breakers) state(language string) CircuitState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if cb, ok := b.breakers[language]; ok {
+		return cb.state
+	}
+	return CircuitClosed
+}
+
+// CircuitState 返回 language 的熔断器状态，未配置 Config.CircuitBreaker 时总是 CircuitClosed
+func (e *CodeExecutor) CircuitState(language string) CircuitState {
+	return e.breakers.state(language)
+}
@@ -0,0 +1,117 @@
+package sandbox
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// BackendBubblewrap 在 bubblewrap 构建的沙箱中运行解释器，仅支持 Linux
+const BackendBubblewrap = "bwrap"
+
+// BwrapConfig 是 bubblewrap 后端的配置
+type BwrapConfig struct {
+	Path string // bwrap 可执行文件路径，为空时使用 "bwrap"
+
+	// ReadOnlyBinds 是以只读方式挂载进沙箱的宿主机路径，为空时挂载整个根目录 "/"。
+	// 只挂载部分目录时需要包含解释器及其标准库，例如 /usr 与 Python 的安装前缀
+	ReadOnlyBinds []string
+
+	// ExtraArgs 追加在默认参数之后、解释器命令之前，例如 --setenv、--bind
+	ExtraArgs []string
+}
+
+// checkBwrapAvailable 检查 bwrap 是否可用
+func checkBwrapAvailable(bwrapPath string) bool {
+	cmd := exec.Command(bwrapPath, "--version")
+	err := cmd.Run()
+	return err == nil
+}
+
+// bwrapRunners 返回 bubblewrap 后端下各语言的 Runner：执行方式与进程后端相同，
+// 由 runUserCommand 为解释器套上 bwrap。bwrap 不可用时所有语言都不可用
+func (e *CodeExecutor) bwrapRunners(config BwrapConfig) map[string]Runner {
+	if config.Path == "" {
+		config.Path = "bwrap"
+	}
+	e.bwrap = &config
+
+	runners := e.builtinRunners()
+	if !checkBwrapAvailable(config.Path) {
+		for language, r := range runners {
+			builtin := *r.(*builtinRunner)
+			builtin.name = "bubblewrap"
+			builtin.available = func() bool { return false }
+			runners[language] = &builtin
+		}
+	}
+	return runners
+}
+
+// wrapBubblewrap 将 cmd 改写为在 bwrap 中执行原命令：根目录只读，/tmp 为私有 tmpfs，
+// 取消全部命名空间（shareNet 为 true 时保留网络命名空间）并丢弃所有 capability。工作目录以读写方式挂载，
+// codePaths 中的代码文件以只读方式挂载到沙箱内的同一路径
+func wrapBubblewrap(cmd *exec.Cmd, config BwrapConfig, workDir string, codePaths []string, shareNet bool) {
+	binds := config.ReadOnlyBinds
+	if len(binds) == 0 {
+		binds = []string{"/"}
+	}
+
+	var args []string
+	for _, path := range binds {
+		args = append(args, "--ro-bind", path, path)
+	}
+	args = append(args,
+		"--dev", "/dev",
+		"--proc", "/proc",
+		"--tmpfs", "/tmp",
+		"--unshare-all",
+		"--die-with-parent",
+		"--cap-drop", "ALL",
+	)
+	if shareNet {
+		args = append(args, "--share-net")
+	}
+	for _, path := range codePaths {
+		args = append(args, "--ro-bind", path, path)
+	}
+	if workDir != "" {
+		args = append(args, "--bind", workDir, workDir, "--chdir", workDir)
+	}
+	args = append(args, config.ExtraArgs...)
+	args = append(args, "--", cmd.Path)
+	args = append(args, cmd.Args[1:]...)
+
+	cmd.Path = config.Path
+	cmd.Args = append([]string{config.Path}, args...)
+}
+
+// tempPaths 返回 args 中位于临时目录 tmp 下且存在的路径。
+// 直接位于临时目录中的文件只挂载文件本身，位于其子目录中的挂载整个子目录
+func tempPaths(tmp string, args []string) []string {
+	tmp = filepath.Clean(tmp)
+	seen := make(map[string]bool)
+	var paths []string
+	for _, arg := range args {
+		if !filepath.IsAbs(arg) {
+			continue
+		}
+		rel, err := filepath.Rel(tmp, arg)
+		if err != nil || rel == "." || !filepath.IsLocal(rel) {
+			continue
+		}
+		if _, err := os.Stat(arg); err != nil {
+			continue
+		}
+		path := arg
+		if first, _, nested := strings.Cut(filepath.ToSlash(rel), "/"); nested {
+			path = filepath.Join(tmp, first)
+		}
+		if !seen[path] {
+			seen[path] = true
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
@@ -0,0 +1,166 @@
+package sandbox
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// CacheStats 是结果缓存的统计信息
+type CacheStats struct {
+	Hits    uint64 `json:"hits"`
+	Misses  uint64 `json:"misses"`
+	Entries int    `json:"entries"`
+}
+
+// resultCache 是按 LRU 淘汰、带过期时间的执行结果缓存，可并发使用
+type resultCache struct {
+	mu         sync.Mutex
+	maxEntries int
+	ttl        time.Duration
+	entries    map[string]*list.Element
+	order      *list.List // 队首为最近使用的条目
+	hits       uint64
+	misses     uint64
+}
+
+type cacheEntry struct {
+	key     string
+	result  ExecutionResult
+	expires time.Time // 零值表示永不过期
+}
+
+func newResultCache(maxEntries int, ttl time.Duration) *resultCache {
+	return &resultCache{
+		maxEntries: maxEntries,
+		ttl:        ttl,
+		entries:    make(map[string]*list.Element),
+		order:      list.New(),
+	}
+}
+
+// cacheKey 根据语言、代码、标准输入和环境变量计算缓存键
+func cacheKey(language string, code string, opts ExecOptions) string {
+	h := sha256.New()
+	write := func(s string) {
+		h.Write([]byte(s))
+		h.Write([]byte{0})
+	}
+	write(language)
+	write(code)
+	write(opts.Stdin)
+	write(opts.PythonVersion)
+	write(strconv.Itoa(len(opts.Args)))
+	for _, arg := range opts.Args {
+		write(arg)
+	}
+	write(strconv.Itoa(len(opts.Packages)))
+	for _, pkg := range opts.Packages {
+		write(pkg)
+	}
+	if opts.InheritEnv {
+		write("inherit")
+	}
+	if opts.CombineOutput {
+		write("combine")
+	}
+	if opts.StripANSI {
+		write("strip-ansi")
+	}
+	keys := make([]string, 0, len(opts.Env))
+	for key := range opts.Env {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		write(key + "=" + opts.Env[key])
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// cacheable 判断结果能否缓存：只缓存成功或由用户代码本身导致失败的完整结果，
+// 超时、取消、输出被截断及沙箱自身故障的结果都不缓存
+func cacheable(result ExecutionResult) bool {
+	if result.Success {
+		return true
+	}
+	switch result.ErrorKind {
+	case ErrorKindCompileError, ErrorKindRuntimeError, ErrorKindMemoryLimit, ErrorKindCPULimit, ErrorKindPolicyViolation:
+		return true
+	}
+	return false
+}
+
+func (c *resultCache) get(key string) (ExecutionResult, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[key]
+	if ok {
+		entry := elem.Value.(*cacheEntry)
+		if entry.expires.IsZero() || time.Now().Before(entry.expires) {
+			c.order.MoveToFront(elem)
+			c.hits++
+			return entry.result, true
+		}
+		c.order.Remove(elem)
+		delete(c.entries, key)
+	}
+	c.misses++
+	return ExecutionResult{}, false
+}
+
+func (c *resultCache) put(key string, result ExecutionResult) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry := &cacheEntry{key: key, result: result}
+	if c.ttl > 0 {
+		entry.expires = time.Now().Add(c.ttl)
+	}
+	if elem, ok := c.entries[key]; ok {
+		elem.Value = entry
+		c.order.MoveToFront(elem)
+		return
+	}
+	c.entries[key] = c.order.PushFront(entry)
+	for c.order.Len() > c.maxEntries {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).key)
+	}
+}
+
+func (c *resultCache) clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries = make(map[string]*list.Element)
+	c.order.Init()
+}
+
+func (c *resultCache) stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return CacheStats{Hits: c.hits, Misses: c.misses, Entries: c.order.Len()}
+}
+
+// ClearCache 清空结果缓存，未启用缓存时为空操作
+func (e *CodeExecutor) ClearCache() {
+	if e.cache != nil {
+		e.cache.clear()
+	}
+}
+
+// CacheStats 返回结果缓存的命中统计，未启用缓存时返回零值
+func (e *CodeExecutor) CacheStats() CacheStats {
+	if e.cache == nil {
+		return CacheStats{}
+	}
+	return e.cache.stats()
+}
@@ -0,0 +1,47 @@
+package sandbox
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+)
+
+// newExecutionID 生成一个随机的执行ID
+func newExecutionID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// track 登记执行 id 的取消函数，id 已被占用时返回 false
+func (e *CodeExecutor) track(id string, cancel context.CancelFunc) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if _, exists := e.running[id]; exists {
+		return false
+	}
+	e.running[id] = cancel
+	return true
+}
+
+// untrack 在执行结束后移除 id 的登记
+func (e *CodeExecutor) untrack(id string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	delete(e.running, id)
+}
+
+// Cancel 取消正在排队或运行中的执行，终止其进程组并释放工作池令牌。
+// id 不存在（未开始或已结束）时返回 false
+func (e *CodeExecutor) Cancel(id string) bool {
+	e.mu.Lock()
+	cancel, ok := e.running[id]
+	e.mu.Unlock()
+
+	if ok {
+		cancel()
+	}
+	return ok
+}
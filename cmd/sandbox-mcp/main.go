@@ -0,0 +1,30 @@
+// sandbox-mcp 是通过标准输入输出提供 execute_code 工具的 MCP 服务端示例，
+// 可直接配置为 MCP 客户端的 stdio 服务器
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	sandbox "github.com/open-mcp-app/mcp-server-sandbox"
+	"github.com/open-mcp-app/mcp-server-sandbox/sandboxmcp"
+)
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	executor := sandbox.New(sandbox.WithOutputLimit(1 << 20))
+	defer executor.Shutdown(context.Background())
+
+	server := sandboxmcp.NewServer("mcp-server-sandbox", "0.1.0")
+	sandboxmcp.Register(server, executor)
+	// 标准输出用于协议消息，日志只能写到标准错误
+	log.SetOutput(os.Stderr)
+	if err := server.ServeStdio(ctx); err != nil && ctx.Err() == nil {
+		log.Fatal(err)
+	}
+}
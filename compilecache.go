@@ -0,0 +1,255 @@
+package sandbox
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// compileCache 是按 LRU 淘汰、带过期时间的编译产物缓存，可并发使用。每个条目是一个包含源文件与
+// 编译产物的目录，位于执行器首次写入时在临时目录下创建的 sandbox-compile-* 中。
+// 条目被淘汰时若仍有执行在使用它，目录在最后一个使用者释放后才删除。
+// 条目由服务进程的用户所有且只读，以 RunAsUser 运行的代码可以执行但不能修改其中的编译产物，
+// 因此条目不参与 dropPrivileges 的属主修改，见 split
+type compileCache struct {
+	mu         sync.Mutex
+	maxEntries int
+	ttl        time.Duration
+	tempRoot   string
+	root       string // 为空表示尚未创建
+	seq        uint64 // 下一个条目目录的序号
+	entries    map[string]*list.Element
+	order      *list.List // 队首为最近使用的条目
+	hits       uint64
+	misses     uint64
+}
+
+type compileEntry struct {
+	key     string
+	dir     string    // 编译产物所在的目录
+	src     string    // 编译时源文件的原始路径，编译产物中记录的是这个路径
+	expires time.Time // 零值表示永不过期
+	refs    int       // 正在使用该目录的执行数
+	evicted bool      // 已移出缓存，refs 降为 0 时删除目录
+}
+
+func newCompileCache(maxEntries int, ttl time.Duration, tempRoot string) *compileCache {
+	return &compileCache{
+		maxEntries: maxEntries,
+		ttl:        ttl,
+		tempRoot:   tempRoot,
+		entries:    make(map[string]*list.Element),
+		order:      list.New(),
+	}
+}
+
+// compileCacheKey 根据语言、编译器版本与源代码计算编译缓存键，编译器升级后旧的条目不再命中
+func compileCacheKey(lang compiledLanguage, compilerVersion string, code string) string {
+	h := sha256.New()
+	for _, s := range []string{lang.language, lang.source, compilerVersion, code} {
+		h.Write([]byte(s))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// get 返回 key 对应的条目并登记一次使用，用完后须调用 release
+func (c *compileCache) get(key string) (entry *compileEntry, release func(), ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[key]
+	if ok {
+		entry := elem.Value.(*compileEntry)
+		if entry.expires.IsZero() || time.Now().Before(entry.expires) {
+			c.order.MoveToFront(elem)
+			c.hits++
+			entry.refs++
+			return entry, c.releaser(entry), true
+		}
+		c.evictLocked(elem)
+	}
+	c.misses++
+	return nil, nil, false
+}
+
+// put 把编译好的目录 dir 移入缓存并登记一次使用，src 是编译时的源文件路径。
+// 同一 key 已被并发的执行写入时改用已有的条目，dir 保持原样；移动失败时返回 false，dir 同样保持原样
+func (c *compileCache) put(key string, dir string, src string) (entry *compileEntry, release func(), ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, exists := c.entries[key]; exists {
+		entry := elem.Value.(*compileEntry)
+		c.order.MoveToFront(elem)
+		entry.refs++
+		return entry, c.releaser(entry), true
+	}
+
+	if c.root == "" {
+		root, err := os.MkdirTemp(c.tempRoot, "sandbox-compile-*")
+		if err != nil {
+			return nil, nil, false
+		}
+		// 其他用户只能进入缓存目录，不能列出其中的条目
+		if err := chmodShared(root, 0o711); err != nil {
+			os.Remove(root)
+			return nil, nil, false
+		}
+		c.root = root
+	}
+	if err := shareTree(dir); err != nil {
+		return nil, nil, false
+	}
+	target := filepath.Join(c.root, strconv.FormatUint(c.seq, 10))
+	c.seq++
+	if err := os.Rename(dir, target); err != nil {
+		return nil, nil, false
+	}
+
+	entry = &compileEntry{key: key, dir: target, src: src, refs: 1}
+	if c.ttl > 0 {
+		entry.expires = time.Now().Add(c.ttl)
+	}
+	c.entries[key] = c.order.PushFront(entry)
+	for c.order.Len() > c.maxEntries {
+		c.evictLocked(c.order.Back())
+	}
+	return entry, c.releaser(entry), true
+}
+
+// split 把 args 中位于缓存条目内的路径分离出来，返回其余的参数与这些路径所在的条目目录。
+// c 为 nil（未启用编译缓存）时原样返回 args
+func (c *compileCache) split(args []string) (rest []string, entries []string) {
+	if c == nil {
+		return args, nil
+	}
+	c.mu.Lock()
+	root := c.root
+	c.mu.Unlock()
+	if root == "" {
+		return args, nil
+	}
+
+	for _, arg := range args {
+		rel, err := filepath.Rel(root, arg)
+		if !filepath.IsAbs(arg) || err != nil || rel == "." || !filepath.IsLocal(rel) {
+			rest = append(rest, arg)
+			continue
+		}
+		first, _, _ := strings.Cut(filepath.ToSlash(rel), "/")
+		if entry := filepath.Join(root, first); !slices.Contains(entries, entry) {
+			entries = append(entries, entry)
+		}
+	}
+	return rest, entries
+}
+
+// shareTree 让 root 下的目录对所有用户可读可进入、文件只读，可执行文件保留执行权限
+func shareTree(root string) error {
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		// chmod 会作用于符号链接的目标，如虚拟环境中指向系统解释器的 bin/python
+		if d.Type()&fs.ModeSymlink != 0 {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		mode := os.FileMode(0o444)
+		if d.IsDir() {
+			mode = 0o755
+		} else if info.Mode()&0o111 != 0 {
+			mode = 0o555
+		}
+		return chmodShared(path, mode)
+	})
+}
+
+// chmodShared 修改 path 的权限位；Windows 没有这样的权限位，chmod 只会把文件设为只读、使其无法删除，因此跳过
+func chmodShared(path string, mode os.FileMode) error {
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+	return os.Chmod(path, mode)
+}
+
+// releaser 返回结束一次使用的函数，调用方须持有 c.mu
+func (c *compileCache) releaser(entry *compileEntry) func() {
+	return func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+
+		entry.refs--
+		if entry.evicted && entry.refs == 0 {
+			removeWithRetry(os.RemoveAll, entry.dir)
+		}
+	}
+}
+
+// evictLocked 把 elem 移出缓存，没有执行在使用时立即删除其目录，调用方须持有 c.mu
+func (c *compileCache) evictLocked(elem *list.Element) {
+	entry := elem.Value.(*compileEntry)
+	c.order.Remove(elem)
+	delete(c.entries, entry.key)
+	entry.evicted = true
+	if entry.refs == 0 {
+		removeWithRetry(os.RemoveAll, entry.dir)
+	}
+}
+
+func (c *compileCache) clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for c.order.Len() > 0 {
+		c.evictLocked(c.order.Back())
+	}
+}
+
+// close 清空缓存并删除缓存目录，调用方须保证没有执行仍在使用编译产物
+func (c *compileCache) close() {
+	c.clear()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.root != "" {
+		removeWithRetry(os.RemoveAll, c.root)
+		c.root = ""
+	}
+}
+
+func (c *compileCache) stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return CacheStats{Hits: c.hits, Misses: c.misses, Entries: c.order.Len()}
+}
+
+// ClearCompileCache 清空编译缓存并删除其中的编译产物，正在运行的产物在执行结束后删除。
+// 未启用编译缓存时为空操作
+func (e *CodeExecutor) ClearCompileCache() {
+	if e.compileCache != nil {
+		e.compileCache.clear()
+	}
+}
+
+// CompileCacheStats 返回编译缓存的命中统计，未启用编译缓存时返回零值
+func (e *CodeExecutor) CompileCacheStats() CacheStats {
+	if e.compileCache == nil {
+		return CacheStats{}
+	}
+	return e.compileCache.stats()
+}
@@ -0,0 +1,118 @@
+package sandbox
+
+import (
+	"io/fs"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"strconv"
+)
+
+// credential 是子进程运行时使用的用户与组
+type credential struct {
+	uid uint32
+	gid uint32
+}
+
+// resolveCredential 将用户名或数字UID解析为 credential，并校验用户确实存在。
+// group 为空时使用该用户的主组，否则按组名或数字GID解析
+func resolveCredential(name string, group string) (*credential, error) {
+	u, err := user.Lookup(name)
+	if err != nil {
+		byID, idErr := user.LookupId(name)
+		if idErr != nil {
+			return nil, err
+		}
+		u = byID
+	}
+	gid := u.Gid
+	if group != "" {
+		g, err := user.LookupGroup(group)
+		if err != nil {
+			byID, idErr := user.LookupGroupId(group)
+			if idErr != nil {
+				return nil, err
+			}
+			g = byID
+		}
+		gid = g.Gid
+	}
+
+	uidValue, err := strconv.ParseUint(u.Uid, 10, 32)
+	if err != nil {
+		return nil, err
+	}
+	gidValue, err := strconv.ParseUint(gid, 10, 32)
+	if err != nil {
+		return nil, err
+	}
+	return &credential{uid: uint32(uidValue), gid: uint32(gidValue)}, nil
+}
+
+// dropPrivileges 让 cmd 以 Config.RunAsUser 指定的用户运行，并把工作目录和 codePaths 中代码文件的属主改为该用户，
+// 使其能够读取代码、写入输出。启用 RequireNonRoot 时拒绝以 root 身份运行
+func (e *CodeExecutor) dropPrivileges(cmd *exec.Cmd, workDir string, codePaths []string) error {
+	if e.credentialErr != nil {
+		return e.sandboxError(ErrorKindInternal, MsgRunAsUserInvalid, e.credentialErr)
+	}
+	if e.credential == nil {
+		if e.requireNonRoot && os.Geteuid() == 0 {
+			return e.sandboxError(ErrorKindInternal, MsgRunAsRoot)
+		}
+		return nil
+	}
+	if e.requireNonRoot && e.credential.uid == 0 {
+		return e.sandboxError(ErrorKindInternal, MsgRunAsRoot)
+	}
+
+	if err := setCredential(cmd, e.credential); err != nil {
+		return e.sandboxError(ErrorKindInternal, MsgDropPrivileges, err)
+	}
+	// 只修改工作目录本身；其中的输入文件以 0644 写入，新用户可读
+	if workDir != "" {
+		if err := os.Chown(workDir, int(e.credential.uid), int(e.credential.gid)); err != nil {
+			return e.sandboxError(ErrorKindInternal, MsgDropPrivileges, err)
+		}
+	}
+	for _, path := range codePaths {
+		if err := chownTree(path, e.credential); err != nil {
+			return e.sandboxError(ErrorKindInternal, MsgDropPrivileges, err)
+		}
+	}
+	return nil
+}
+
+// containerUser 返回 Docker 后端传给 --user 的 "uid:gid"，未设置 RunAsUser 时返回空字符串，容器以镜像的默认用户运行。
+// 与 dropPrivileges 一样把挂载为 /sandbox 的工作目录和 codePaths 中代码文件的属主改为该用户：
+// MkdirTemp 创建的目录权限为 0700，否则容器内的进程无法进入工作目录。启用 RequireNonRoot 时拒绝以 uid 0 运行
+func (e *CodeExecutor) containerUser(workDir string, codePaths []string) (string, error) {
+	if e.credentialErr != nil {
+		return "", e.sandboxError(ErrorKindInternal, MsgRunAsUserInvalid, e.credentialErr)
+	}
+	if e.credential == nil {
+		return "", nil
+	}
+	if e.requireNonRoot && e.credential.uid == 0 {
+		return "", e.sandboxError(ErrorKindInternal, MsgRunAsRoot)
+	}
+	if err := os.Chown(workDir, int(e.credential.uid), int(e.credential.gid)); err != nil {
+		return "", e.sandboxError(ErrorKindInternal, MsgDropPrivileges, err)
+	}
+	for _, path := range codePaths {
+		if err := chownTree(path, e.credential); err != nil {
+			return "", e.sandboxError(ErrorKindInternal, MsgDropPrivileges, err)
+		}
+	}
+	return strconv.FormatUint(uint64(e.credential.uid), 10) + ":" + strconv.FormatUint(uint64(e.credential.gid), 10), nil
+}
+
+// chownTree 递归修改 root 及其下所有文件的属主
+func chownTree(root string, c *credential) error {
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		return os.Lchown(path, int(c.uid), int(c.gid))
+	})
+}
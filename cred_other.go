@@ -0,0 +1,13 @@
+//go:build !unix
+
+package sandbox
+
+import (
+	"errors"
+	"os/exec"
+)
+
+// setCredential 在非 Unix 平台上不支持切换运行用户
+func setCredential(cmd *exec.Cmd, c *credential) error {
+	return errors.ErrUnsupported
+}
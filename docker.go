@@ -0,0 +1,188 @@
+package sandbox
+
+import (
+	"context"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// 执行后端，通过 Config.Backend 选择
+const (
+	BackendProcess = "process" // 直接在宿主机上启动解释器（默认）
+	BackendDocker  = "docker"  // 每次执行启动一个一次性的 Docker 容器
+)
+
+// dockerMountPoint 是工作目录在容器内的挂载点
+const dockerMountPoint = "/sandbox"
+
+// dockerLibMountPoint 是 Config.PythonLibDirs 在容器内的挂载目录，第 i 个目录挂载到其下的 i
+const dockerLibMountPoint = "/sandbox-lib"
+
+// DockerConfig 是 Docker 后端的配置
+type DockerConfig struct {
+	Path   string            // docker 可执行文件路径，为空时使用 "docker"
+	Images map[string]string // 按语言覆盖默认镜像
+	CPUs   float64           // 传给 --cpus 的CPU配额，0 表示不限制
+}
+
+// dockerLanguage 描述一个语言在容器中的执行方式
+type dockerLanguage struct {
+	name    string   // 用于提示信息的语言名称
+	image   string   // 默认镜像
+	pattern string   // 代码文件名模板
+	command []string // 在文件路径前的命令
+}
+
+// dockerLanguages 是 Docker 后端支持的语言，键与 supportedLanguages 一致
+var dockerLanguages = map[string]dockerLanguage{
+	"python3":    {"Python", "python:3-slim", "python-*.py", []string{"python"}},
+	"nodejs":     {"Node.js", "node:20-slim", "nodejs-*.js", []string{"node"}},
+	"go":         {"Go", "golang:1.22", "go-*.go", []string{"go", "run"}},
+	"ruby":       {"Ruby", "ruby:3-slim", "ruby-*.rb", []string{"ruby"}},
+	"bash":       {"Bash", "bash:5", "bash-*.sh", []string{"bash"}},
+	"typescript": {"TypeScript", "denoland/deno", "typescript-*.ts", []string{"deno", "run"}},
+	"deno":       {"Deno", "denoland/deno", "deno-*.ts", []string{"deno", "run"}},
+}
+
+// checkDockerAvailable 检查 docker 客户端是否可用，实际能否连接守护进程在执行时才会暴露
+func checkDockerAvailable(dockerPath string) bool {
+	cmd := exec.Command(dockerPath, "--version")
+	err := cmd.Run()
+	return err == nil
+}
+
+// dockerRunners 返回 Docker 后端下各语言的 Runner
+func (e *CodeExecutor) dockerRunners(config DockerConfig) map[string]Runner {
+	if config.Path == "" {
+		config.Path = "docker"
+	}
+	available := checkDockerAvailable(config.Path)
+
+	runners := make(map[string]Runner, len(dockerLanguages))
+	for language, spec := range dockerLanguages {
+		if image, ok := config.Images[language]; ok {
+			spec.image = image
+		}
+		runners[language] = &builtinRunner{
+			name: "Docker",
+			run: func(ctx context.Context, code string, opts ExecOptions) ExecutionResult {
+				return e.runInContainer(ctx, config, spec, code, opts)
+			},
+			available: func() bool { return available },
+		}
+	}
+	return runners
+}
+
+// runInContainer 将工作目录挂载到容器的 /sandbox，以 docker run --rm 执行代码。
+// 容器始终使用 --network none；MaxMemoryBytes 映射为 --memory，MaxCPUSeconds 不适用于容器。
+// 宿主机的环境变量不会传入容器，只传递 presetEnv 与 opts.Env。设置了 RunAsUser 时容器以该用户的 uid:gid 运行，
+// 工作目录与代码文件的属主同样改为该用户，见 containerUser。执行结束后总是强制删除容器，
+// 客户端因超时、取消或输出超限被终止时容器不会残留
+func (e *CodeExecutor) runInContainer(ctx context.Context, config DockerConfig, spec dockerLanguage, code string, opts ExecOptions) ExecutionResult {
+	dir := opts.WorkDir
+	if dir == "" {
+		tmp, err := e.tempFS.MkdirTemp(e.tempDir, "docker-*")
+		if err != nil {
+			return e.fail(ErrorKindInternal, MsgCreateTempDir, err)
+		}
+		defer e.removeTemp(tmp)
+		dir = tmp
+	}
+
+	// 多文件项目的入口文件已经写入工作目录，否则把代码写入工作目录下的临时文件
+	source, temporary := opts.sourceFile, false
+	if source == "" {
+		temporary = true
+		file, err := e.tempFS.CreateTemp(dir, spec.pattern)
+		if err != nil {
+			return e.fail(ErrorKindInternal, MsgCreateTempFile, err)
+		}
+		defer e.removeTemp(file.Name())
+		if _, err := io.WriteString(file, code); err != nil {
+			file.Close()
+			return e.fail(ErrorKindInternal, MsgWriteCode, err)
+		}
+		file.Close()
+		// 容器内的进程未必与宿主机同一用户，代码文件需要可读
+		os.Chmod(file.Name(), 0o644)
+		source = file.Name()
+	}
+	rel, err := filepath.Rel(dir, source)
+	if err != nil {
+		return e.fail(ErrorKindInternal, MsgResolveEntry, err)
+	}
+	var codePaths []string
+	if temporary {
+		codePaths = []string{source}
+	}
+	user, err := e.containerUser(dir, codePaths)
+	if err != nil {
+		return errorResultFrom(err)
+	}
+
+	name := "sandbox-" + newExecutionID()
+	args := []string{"run", "--rm", "-i", "--name", name, "--network", "none",
+		"-v", dir + ":" + dockerMountPoint, "-w", dockerMountPoint}
+	if e.limits.maxMemoryBytes > 0 {
+		memory := strconv.FormatInt(e.limits.maxMemoryBytes, 10)
+		args = append(args, "--memory", memory, "--memory-swap", memory)
+	}
+	if user != "" {
+		args = append(args, "--user", user)
+	}
+	if config.CPUs > 0 {
+		args = append(args, "--cpus", strconv.FormatFloat(config.CPUs, 'f', -1, 64))
+	}
+	for _, kv := range e.presetEnv() {
+		args = append(args, "-e", kv)
+	}
+	keys := make([]string, 0, len(opts.Env))
+	for key := range opts.Env {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		args = append(args, "-e", key+"="+opts.Env[key])
+	}
+	if opts.pythonLib && len(e.pythonLibDirs) > 0 {
+		libs := make([]string, len(e.pythonLibDirs))
+		for i, dir := range e.pythonLibDirs {
+			libs[i] = dockerLibMountPoint + "/" + strconv.Itoa(i)
+			args = append(args, "-v", dir+":"+libs[i]+":ro")
+		}
+		args = append(args, "-e", "PYTHONPATH="+joinPath(opts.Env["PYTHONPATH"], libs, ":"))
+	}
+	args = append(args, spec.image)
+	args = append(args, spec.command...)
+	containerSource := dockerMountPoint + "/" + filepath.ToSlash(rel)
+	args = append(args, containerSource)
+	args = append(args, opts.Args...)
+
+	// docker 客户端本身不受 prlimit 约束，资源限制由容器施加
+	cmd := exec.CommandContext(ctx, config.Path, args...)
+	if opts.Stdin != "" {
+		cmd.Stdin = strings.NewReader(opts.Stdin)
+	}
+	result := e.runWithOutput(ctx, cmd, e.userOutput(cmd, opts))
+	// rusage 反映的是 docker 客户端而不是容器内的进程
+	result.MaxRSSBytes, result.CPUTimeMs = 0, 0
+	// 杀死 docker 客户端不会停止容器，而超时、取消、输出超限与输出洪泛都会杀死客户端。
+	// 容器正常退出时已被 --rm 删除，此时 rm 只是报告容器不存在
+	exec.Command(config.Path, "rm", "-f", name).Run()
+
+	// 容器因超出 --memory 被 OOM killer 终止时退出码为 137
+	if !result.Success && e.limits.maxMemoryBytes > 0 && (result.ExitCode == 137 || memoryExhausted(result.Error)) {
+		result.Error = e.msg(MsgMemoryLimit, e.limits.maxMemoryBytes) + "\n" + result.Error
+		result.ErrorKind = ErrorKindMemoryLimit
+	}
+	if temporary {
+		result = e.rewriteTempPath(result, containerSource, filepath.Ext(spec.pattern))
+	}
+	return result
+}
@@ -0,0 +1,68 @@
+package sandbox
+
+import (
+	"os"
+	"os/user"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// fakeDocker 模拟 docker 客户端：run 时输出 --user 的值，以及挂载目录与代码文件的属主和权限
+const fakeDocker = `#!/bin/sh
+[ "$1" = run ] || exit 0
+shift
+while [ $# -gt 0 ]; do
+	case "$1" in
+	--user) user="$2"; shift ;;
+	-v) mount="${2%%:*}"; shift ;;
+	-w|--name|--network|--memory|--memory-swap|--cpus|-e) shift ;;
+	-*) ;;
+	*) break ;;
+	esac
+	shift
+done
+# 剩余参数为镜像、命令与容器内的代码文件路径
+eval "source=\${$#}"
+echo "user=${user:-none}"
+stat -c "dir=%u:%g" "$mount"
+stat -c "file=%u:%g %a" "$mount/${source#/sandbox/}"
+`
+
+func TestDockerRunAsUserOwnsWorkDir(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("修改属主需要以 root 运行")
+	}
+	nobody, err := user.Lookup("nobody")
+	if err != nil {
+		t.Skip("需要 nobody 用户")
+	}
+	docker := filepath.Join(t.TempDir(), "docker")
+	if err := os.WriteFile(docker, []byte(fakeDocker), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	owner := nobody.Uid + ":" + nobody.Gid
+
+	tests := []struct {
+		name      string
+		runAsUser string
+		want      []string
+	}{
+		{"default user", "", []string{"user=none", "dir=0:0", "file=0:0 644"}},
+		{"run as user", "nobody", []string{"user=" + owner, "dir=" + owner, "file=" + owner + " 644"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := Config{Backend: BackendDocker, Docker: DockerConfig{Path: docker}, TempDir: t.TempDir(), RunAsUser: tt.runAsUser}
+			e := newTestExecutor(t, "python3", config)
+			result := e.Execute(`print("hi")`, "python3")
+			if !result.Success {
+				t.Fatalf("执行失败: %s", result.Error)
+			}
+			if got := strings.Split(strings.TrimSpace(result.Output), "\n"); !slices.Equal(got, tt.want) {
+				t.Errorf("容器看到 %q，期望 %q", got, tt.want)
+			}
+		})
+	}
+}
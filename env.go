@@ -0,0 +1,138 @@
+package sandbox
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// baseEnvKeys 是 Config.EnvAllowlist 未设置时子进程从父进程继承的最小环境变量集合，
+// 其余变量（如云厂商凭证）不会泄露给被执行的代码
+var baseEnvKeys = []string{"PATH", "HOME", "TMPDIR", "LANG"}
+
+// 未指定 Config.Lang 与 Config.Timezone 时子进程使用的区域设置与时区，
+// 使日期、数字的格式化结果不随宿主机变化
+const (
+	defaultLang     = "C.UTF-8"
+	defaultTimezone = "UTC"
+)
+
+// localeEnv 返回设置子进程区域与时区的环境变量，Config.InheritLocale 为 true 时返回 nil
+func (e *CodeExecutor) localeEnv() []string {
+	if e.inheritLocale {
+		return nil
+	}
+	return []string{"LANG=" + e.lang, "LC_ALL=" + e.lang, "TZ=" + e.timezone}
+}
+
+// dnsBlockEnv 是 Config.BlockDNS 注入的环境变量：attempts:0 让 glibc 的解析器一次查询也不发送，
+// getaddrinfo 对 /etc/hosts 以外的名称立即失败；netdns=cgo 让 Go 程序同样使用 glibc 的解析器
+var dnsBlockEnv = []string{"RES_OPTIONS=attempts:0", "GODEBUG=netdns=cgo"}
+
+// presetEnv 返回执行器为每个子进程设置的环境变量：区域与时区，以及启用 BlockDNS 时的 dnsBlockEnv
+func (e *CodeExecutor) presetEnv() []string {
+	env := e.localeEnv()
+	if e.blockDNS {
+		env = append(env, dnsBlockEnv...)
+	}
+	return env
+}
+
+// buildEnv 根据 opts 构造子进程的环境变量。
+// Config.InheritEnv 或 opts.InheritEnv 为 true 时以 os.Environ() 为基础，否则只保留允许列表中的变量；
+// 随后追加 presetEnv 中的区域、时区与 DNS 设置（安装依赖时不含 DNS 设置）；opts.Env 中的变量最后追加，同名时覆盖前面的值。
+// 不继承 PATH 时（见 Config.InheritPath）PATH 改为 interpreterDir 与标准目录；Python 代码的 PYTHONPATH 末尾再加上 Config.PythonLibDirs
+func (e *CodeExecutor) buildEnv(opts ExecOptions, interpreterDir string) []string {
+	// 非 nil 的空切片表示空环境，nil 会让 exec 继承完整的父进程环境
+	env := []string{}
+	inheritAll := e.inheritEnv || opts.InheritEnv
+	if inheritAll {
+		env = append(env, os.Environ()...)
+	} else {
+		for _, key := range e.envAllowlist {
+			if value, ok := os.LookupEnv(key); ok {
+				env = append(env, key+"="+value)
+			}
+		}
+	}
+	// 安装依赖需要解析包索引的域名，不注入 BlockDNS 的变量
+	if opts.install {
+		env = append(env, e.localeEnv()...)
+	} else {
+		env = append(env, e.presetEnv()...)
+	}
+	if !e.inheritPath && !inheritAll {
+		dirs := standardPath()
+		if slices.Contains(dirs, interpreterDir) {
+			interpreterDir = ""
+		}
+		env = append(env, "PATH="+joinPath(interpreterDir, dirs, string(filepath.ListSeparator)))
+	}
+
+	keys := make([]string, 0, len(opts.Env))
+	for key := range opts.Env {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		env = append(env, key+"="+opts.Env[key])
+	}
+	if opts.pythonLib && len(e.pythonLibDirs) > 0 {
+		env = append(env, "PYTHONPATH="+joinPath(lookupEnv(env, "PYTHONPATH"), e.pythonLibDirs, string(filepath.ListSeparator)))
+	}
+	return env
+}
+
+// standardPath 返回子进程默认 PATH 中的系统标准目录
+func standardPath() []string {
+	if runtime.GOOS == "windows" {
+		root := os.Getenv("SystemRoot")
+		if root == "" {
+			root = `C:\Windows`
+		}
+		return []string{filepath.Join(root, "System32"), root}
+	}
+	return []string{"/usr/local/bin", "/usr/bin", "/bin"}
+}
+
+// lookupEnv 返回 env 中 key 最后一次出现的值，不存在时返回空字符串
+func lookupEnv(env []string, key string) string {
+	for i := len(env) - 1; i >= 0; i-- {
+		if value, ok := strings.CutPrefix(env[i], key+"="); ok {
+			return value
+		}
+	}
+	return ""
+}
+
+// joinPath 把 dirs 以 sep 连接在 existing 之后，existing 为空时只连接 dirs
+func joinPath(existing string, dirs []string, sep string) string {
+	if existing != "" {
+		dirs = append([]string{existing}, dirs...)
+	}
+	return strings.Join(dirs, sep)
+}
+
+// resolveLibDirs 把 dirs 转换为绝对路径，并检查它们都存在且是目录
+func resolveLibDirs(dirs []string) ([]string, error) {
+	resolved := make([]string, 0, len(dirs))
+	for _, dir := range dirs {
+		abs, err := filepath.Abs(dir)
+		if err != nil {
+			return nil, err
+		}
+		info, err := os.Stat(abs)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			return nil, fmt.Errorf("%s: not a directory", abs)
+		}
+		resolved = append(resolved, abs)
+	}
+	return resolved, nil
+}
@@ -0,0 +1,59 @@
+package sandbox
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExecOptionsEnv(t *testing.T) {
+	env := map[string]string{"API_BASE": "https://api.example.com/v1", "QUOTED": `a "b" $c`}
+	tests := []struct {
+		language string
+		code     string
+	}{
+		{"python3", `import os; print(os.environ["API_BASE"]); print(os.environ["QUOTED"])`},
+		{"nodejs", `console.log(process.env.API_BASE); console.log(process.env.QUOTED)`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.language, func(t *testing.T) {
+			e := newTestExecutor(t, tt.language, Config{})
+			result := e.ExecuteWithOptions(tt.code, tt.language, ExecOptions{Env: env})
+			if !result.Success {
+				t.Fatalf("执行失败: %s", result.Error)
+			}
+			want := env["API_BASE"] + "\n" + env["QUOTED"]
+			if got := strings.TrimSpace(result.Output); got != want {
+				t.Errorf("输出 %q，期望 %q", got, want)
+			}
+		})
+	}
+}
+
+func TestSecretEnvNotInherited(t *testing.T) {
+	t.Setenv("SANDBOX_TEST_SECRET", "s3cr3t")
+	const code = `import os; print(os.environ.get("SANDBOX_TEST_SECRET", "<unset>"))`
+	tests := []struct {
+		name   string
+		config Config
+		opts   ExecOptions
+		want   string
+	}{
+		{"default", Config{}, ExecOptions{}, "<unset>"},
+		{"empty allowlist", Config{EnvAllowlist: []string{}}, ExecOptions{}, "<unset>"},
+		{"allowlisted", Config{EnvAllowlist: []string{"PATH", "SANDBOX_TEST_SECRET"}}, ExecOptions{}, "s3cr3t"},
+		{"config inherit", Config{InheritEnv: true}, ExecOptions{}, "s3cr3t"},
+		{"per-execution inherit", Config{}, ExecOptions{InheritEnv: true}, "s3cr3t"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newTestExecutor(t, "python3", tt.config)
+			result := e.ExecuteWithOptions(code, "python3", tt.opts)
+			if !result.Success {
+				t.Fatalf("执行失败: %s", result.Error)
+			}
+			if got := strings.TrimSpace(result.Output); got != tt.want {
+				t.Errorf("子进程看到 SANDBOX_TEST_SECRET=%q，期望 %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package sandbox
+
+import "errors"
+
+// ErrorKind 是执行失败原因的机器可读分类，成功的执行为空字符串
+type ErrorKind string
+
+const (
+	ErrorKindInternal            ErrorKind = "internal"             // 沙箱自身的故障，如创建临时文件失败
+	ErrorKindInvalidRequest      ErrorKind = "invalid_request"      // 请求参数非法，如文件名越出工作目录
+	ErrorKindLanguageUnsupported ErrorKind = "language_unsupported" // 不支持的语言
+	ErrorKindRuntimeUnavailable  ErrorKind = "runtime_unavailable"  // 语言受支持但解释器未安装
+	ErrorKindCompileError        ErrorKind = "compile_error"        // 代码未能通过编译
+	ErrorKindInstallError        ErrorKind = "install_error"        // ExecOptions.Packages 中的依赖未能安装
+	ErrorKindRuntimeError        ErrorKind = "runtime_error"        // 用户代码以非零状态退出或崩溃
+	ErrorKindTimeout             ErrorKind = "timeout"              // 超过墙钟超时时间
+	ErrorKindCanceled            ErrorKind = "canceled"             // 被调用方取消
+	ErrorKindOutputLimit         ErrorKind = "output_limit"         // 输出超出 MaxOutputBytes
+	ErrorKindOutputFlood         ErrorKind = "output_flood"         // 输出速率持续超出 MaxOutputRate
+	ErrorKindMemoryLimit         ErrorKind = "memory_limit"         // 超出 MaxMemoryBytes
+	ErrorKindCPULimit            ErrorKind = "cpu_limit"            // 超出 MaxCPUSeconds
+	ErrorKindOutOfMemory         ErrorKind = "out_of_memory"        // 被内核的 OOM killer 终止，通常是内存占用超出了系统或 cgroup 的上限
+	ErrorKindQueueFull           ErrorKind = "queue_full"           // 异步任务队列已满
+	ErrorKindBusy                ErrorKind = "busy"                 // 工作池已满且超过 QueueTimeout
+	ErrorKindShuttingDown        ErrorKind = "shutting_down"        // 执行器已调用 Shutdown
+	ErrorKindPolicyViolation     ErrorKind = "policy_violation"     // 代码违反了如导入策略之类的安全策略，未被执行
+	ErrorKindCodeTooLarge        ErrorKind = "code_too_large"       // 源代码超出 MaxCodeBytes
+	ErrorKindRateLimited         ErrorKind = "rate_limited"         // 客户端的执行频率超出 RateLimiter 的限制
+)
+
+// ErrorPhase 标识执行失败发生在哪个阶段，便于界面区分"代码未通过编译"与"程序运行时崩溃"
+type ErrorPhase string
+
+const (
+	PhaseSetup   ErrorPhase = "setup"   // 代码开始运行之前，如参数非法、运行时不可用、排队被拒绝
+	PhaseInstall ErrorPhase = "install" // 安装 ExecOptions.Packages 中的依赖
+	PhaseCompile ErrorPhase = "compile" // 编译型语言的构建阶段
+	PhaseRuntime ErrorPhase = "runtime" // 程序运行期间，包括触发资源限制与被取消
+	PhaseTimeout ErrorPhase = "timeout" // 超过墙钟超时时间后被终止
+)
+
+// phaseOf 根据失败结果的分类推断失败阶段，成功时返回空字符串
+func phaseOf(result ExecutionResult) ErrorPhase {
+	if result.Success {
+		return ""
+	}
+	switch result.ErrorKind {
+	case ErrorKindInstallError:
+		return PhaseInstall
+	case ErrorKindCompileError:
+		return PhaseCompile
+	case ErrorKindTimeout:
+		return PhaseTimeout
+	case ErrorKindRuntimeError, ErrorKindOutputLimit, ErrorKindOutputFlood, ErrorKindMemoryLimit, ErrorKindCPULimit, ErrorKindOutOfMemory,
+		ErrorKindCanceled, "":
+		return PhaseRuntime
+	}
+	return PhaseSetup
+}
+
+// SandboxError 是带有分类的执行错误。
+// errors.Is 按 Kind 比较，因此 errors.Is(err, ErrTimeout) 对任意超时错误都成立
+type SandboxError struct {
+	Kind    ErrorKind
+	Message string // 面向用户的错误描述
+
+	transient bool // 由临时的系统资源故障导致，见 RetryPolicy
+}
+
+func (e *SandboxError) Error() string {
+	return e.Message
+}
+
+// Is 使 errors.Is 按错误分类匹配哨兵错误
+func (e *SandboxError) Is(target error) bool {
+	t, ok := target.(*SandboxError)
+	return ok && t.Kind == e.Kind
+}
+
+// 各分类的哨兵错误，用于 errors.Is 判断。执行器返回的错误是按 Config.Locale 本地化的 *SandboxError，
+// 与哨兵错误分类相同但消息不同，不应直接比较或展示哨兵错误的 Message
+var (
+	ErrTimeout             = &SandboxError{Kind: ErrorKindTimeout, Message: "代码执行超时"}
+	ErrCanceled            = &SandboxError{Kind: ErrorKindCanceled, Message: "代码执行已取消"}
+	ErrLanguageUnsupported = &SandboxError{Kind: ErrorKindLanguageUnsupported, Message: "不支持的语言"}
+	ErrRuntimeUnavailable  = &SandboxError{Kind: ErrorKindRuntimeUnavailable, Message: "运行时未安装或不可用"}
+	ErrQueueFull           = &SandboxError{Kind: ErrorKindQueueFull, Message: "任务队列已满"}
+	ErrBusy                = &SandboxError{Kind: ErrorKindBusy, Message: "没有空闲的执行槽位"}
+	ErrShuttingDown        = &SandboxError{Kind: ErrorKindShuttingDown, Message: "执行器正在关闭"}
+	ErrPolicyViolation     = &SandboxError{Kind: ErrorKindPolicyViolation, Message: "代码违反安全策略"}
+	ErrCodeTooLarge        = &SandboxError{Kind: ErrorKindCodeTooLarge, Message: "代码大小超出限制"}
+	ErrRateLimited         = &SandboxError{Kind: ErrorKindRateLimited, Message: "执行频率超出限制"}
+)
+
+// Err 将失败的执行结果转换为 *SandboxError，成功时返回 nil
+func (r ExecutionResult) Err() error {
+	if r.Success {
+		return nil
+	}
+	kind := r.ErrorKind
+	if kind == "" {
+		kind = ErrorKindRuntimeError
+	}
+	return &SandboxError{Kind: kind, Message: r.Error}
+}
+
+// errorResult 构造一个指定分类的失败结果
+func errorResult(kind ErrorKind, message string) ExecutionResult {
+	result := ExecutionResult{
+		Success:   false,
+		Error:     message,
+		ErrorKind: kind,
+	}
+	result.ErrorPhase = phaseOf(result)
+	return result
+}
+
+// errorResultFrom 根据 err 构造失败结果，非 *SandboxError 的错误归为内部错误
+func errorResultFrom(err error) ExecutionResult {
+	var sandboxErr *SandboxError
+	if errors.As(err, &sandboxErr) {
+		result := errorResult(sandboxErr.Kind, sandboxErr.Message)
+		result.transient = sandboxErr.transient
+		return result
+	}
+	return errorResult(ErrorKindInternal, err.Error())
+}
@@ -0,0 +1,58 @@
+package sandbox
+
+import "time"
+
+// EventType 标识执行生命周期中的事件
+type EventType string
+
+const (
+	EventStarted  EventType = "started"  // 已获得工作池令牌，开始运行代码
+	EventFinished EventType = "finished" // 执行结束（超时与被拒绝以外的所有结果，包括缓存命中与参数错误）
+	EventTimeout  EventType = "timeout"  // 执行因超时被终止
+	EventRejected EventType = "rejected" // 任务因 Submit 队列已满（ErrorKindQueueFull）或工作池已满（ErrorKindBusy）未被接受
+)
+
+// ExecEvent 描述一次执行生命周期事件，Started 事件只填充 ID 与 Language，Rejected 事件只填充 ID、Language 与 ErrorKind，
+// Submit 拒绝的任务没有 ID
+type ExecEvent struct {
+	Type      EventType
+	ID        string
+	Language  string
+	Duration  time.Duration
+	Success   bool
+	ExitCode  int
+	ErrorKind ErrorKind
+	Cached    bool // 结果来自缓存，未实际运行代码
+}
+
+// emit 将事件交给 Config.OnEvent，未设置时为空操作
+func (e *CodeExecutor) emit(event ExecEvent) {
+	if e.onEvent != nil {
+		e.onEvent(event)
+	}
+}
+
+// finish 记录执行结果的计数，并根据结果发送 Finished、Timeout 或 Rejected 事件
+func (e *CodeExecutor) finish(language string, result ExecutionResult, cached bool) {
+	e.metrics.record(language, result)
+	eventType := EventFinished
+	switch result.ErrorKind {
+	case ErrorKindTimeout:
+		eventType = EventTimeout
+	case ErrorKindBusy:
+		// 工作池已满时没有运行代码，与 Submit 队列已满一样按被拒绝统计
+		e.metrics.rejected.Add(1)
+		e.emit(ExecEvent{Type: EventRejected, ID: result.ID, Language: language, ErrorKind: result.ErrorKind})
+		return
+	}
+	e.emit(ExecEvent{
+		Type:      eventType,
+		ID:        result.ID,
+		Language:  language,
+		Duration:  time.Duration(result.DurationMs) * time.Millisecond,
+		Success:   result.Success,
+		ExitCode:  result.ExitCode,
+		ErrorKind: result.ErrorKind,
+		Cached:    cached,
+	})
+}
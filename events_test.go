@@ -0,0 +1,65 @@
+package sandbox
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPoolBusyEmitsRejected(t *testing.T) {
+	tests := []struct {
+		name         string
+		queueTimeout time.Duration
+	}{
+		{"immediate", -1},
+		{"queue timeout", 100 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				mu     sync.Mutex
+				events []ExecEvent
+			)
+			onEvent := func(event ExecEvent) {
+				mu.Lock()
+				defer mu.Unlock()
+				events = append(events, event)
+			}
+			e := newTestExecutor(t, "python3", Config{MaxWorkers: 1, QueueTimeout: tt.queueTimeout, OnEvent: onEvent})
+
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				e.Execute("import time; time.sleep(2)", "python3")
+			}()
+			for deadline := time.Now().Add(5 * time.Second); e.Stats().InFlight == 0; time.Sleep(10 * time.Millisecond) {
+				if time.Now().After(deadline) {
+					t.Fatal("执行没有开始")
+				}
+			}
+			result := e.Execute(`print("busy")`, "python3")
+			<-done
+			if result.ErrorKind != ErrorKindBusy {
+				t.Fatalf("ErrorKind = %q，期望 %q（%s）", result.ErrorKind, ErrorKindBusy, result.Error)
+			}
+			if got := e.Metrics().Rejected; got != 1 {
+				t.Errorf("Rejected = %d，期望 1", got)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			var types []EventType
+			for _, event := range events {
+				if event.ID == result.ID {
+					types = append(types, event.Type)
+					if event.ErrorKind != ErrorKindBusy {
+						t.Errorf("事件 ErrorKind = %q，期望 %q", event.ErrorKind, ErrorKindBusy)
+					}
+				}
+			}
+			if len(types) != 1 || types[0] != EventRejected {
+				t.Errorf("被拒绝的执行发出事件 %v，期望只有 %v", types, EventRejected)
+			}
+		})
+	}
+}
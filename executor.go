@@ -3,190 +3,1606 @@ package sandbox
 import (
 	"bytes"
 	"context"
-	"fmt"
+	"errors"
+	"io"
+	"maps"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
+
+	"go.opentelemetry.io/otel/trace"
 )
 
 // ExecutionResult 表示代码执行的结果
 type ExecutionResult struct {
-	Success bool   `json:"success"`
-	Output  string `json:"output"`
-	Error   string `json:"error"`
+	ID         string     `json:"id"` // 执行ID，可用于 Cancel
+	Success    bool       `json:"success"`
+	Output     string     `json:"output"`
+	Error      string     `json:"error"`
+	Stderr     string     `json:"stderr,omitempty"`      // 进程写入标准错误的全部内容，成功时同样保留
+	ErrorKind  ErrorKind  `json:"error_kind,omitempty"`  // 失败原因的分类，成功时为空
+	ExitCode   int        `json:"exit_code"`             // 进程退出码，被信号终止时为 -1
+	Signal     string     `json:"signal,omitempty"`      // 终止进程的信号，如 SIGKILL、SIGSEGV，正常退出时为空
+	ErrorPhase ErrorPhase `json:"error_phase,omitempty"` // 失败发生的阶段，成功时为空
+	DurationMs int64      `json:"duration_ms"`           // 执行耗时（毫秒），失败和超时时同样记录
+	Truncated  bool       `json:"truncated"`             // 输出超出 MaxOutputBytes 被截断，进程已被终止
+
+	// StartedAt 与 FinishedAt 是获得工作池令牌后开始运行与运行结束的时刻，编译型语言包含编译阶段，
+	// JSON 中为 RFC 3339 格式；代码未进入运行（如参数非法、排队被拒绝）或命中缓存时为零值。
+	// QueueWaitMs 是等待工作池令牌的时长（毫秒），排队被拒绝或取消时同样记录
+	StartedAt   time.Time `json:"started_at"`
+	FinishedAt  time.Time `json:"finished_at"`
+	QueueWaitMs int64     `json:"queue_wait_ms,omitempty"`
+
+	// InvalidUTF8 表示进程的输出不是合法的 UTF-8（如写出了二进制数据），Output、Stderr 与 Error 中的
+	// 每段连续的非法字节已替换为一个 U+FFFD，结果总能被安全地序列化为 JSON。需要原始字节时应写入文件并用 CollectFiles 回收
+	InvalidUTF8 bool `json:"invalid_utf8,omitempty"`
+
+	// MaxRSSBytes 是进程（含其已退出的子进程）的峰值常驻内存，取自 rusage 的 ru_maxrss，仅供参考：
+	// 不支持的平台（Windows）、docker 后端、代码未运行或进程在超时后仍未退出时为 0
+	MaxRSSBytes int64 `json:"max_rss_bytes,omitempty"`
+	// CPUTimeMs 是进程（含其已退出的子进程）消耗的用户态与内核态 CPU 时间之和（毫秒），
+	// 与 DurationMs 相比可以看出代码是 CPU 密集还是在等待；无法获取时为 0，docker 后端同样为 0
+	CPUTimeMs int64 `json:"cpu_time_ms,omitempty"`
+
+	Language       string `json:"language"`                  // 请求的语言
+	RuntimeVersion string `json:"runtime_version,omitempty"` // 执行代码的运行时版本，如 "Python 3.11.9"；代码未运行或版本未知时为空
+
+	Files          []OutputFile `json:"files,omitempty"`           // 按 CollectFiles 回收的文件
+	FilesTruncated bool         `json:"files_truncated,omitempty"` // 部分文件因超出 MaxCollectBytes 未被回收
+
+	// Attempts 是本次请求进入执行流程的次数，通常为 1，配置了 Config.Retry 且发生重试时大于 1；
+	// 因限流、关闭或 ID 重复被拒绝以及命中缓存时为 0
+	Attempts int `json:"attempts,omitempty"`
+
+	// transient 为 true 表示失败由临时的系统资源故障导致，可以按 RetryPolicy 重试
+	transient bool
+}
+
+// Config 是创建 CodeExecutor 时使用的配置
+type Config struct {
+	Timeout    time.Duration // 单次执行的默认超时时间，小于等于 0 时使用 30 秒，可按语言覆盖，见 LanguageTimeouts
+	MaxWorkers int           // 最大并发执行数，小于等于 0 时使用 CPU 核数
+	PythonPath string        // Python解释器路径，为空时在 PATH 中依次查找 python、python3（Windows 上还有 py 启动器）
+	NodePath   string        // Node.js解释器路径，为空时在 PATH 中查找 node，非 Windows 平台还会尝试 nodejs
+	NpmPath    string        // npm 路径，用于安装 Node.js 的 Packages，为空时在 PATH 中查找 npm
+
+	// PythonLibDirs 是加入 Python 代码 PYTHONPATH 的宿主机目录，代码无需安装即可导入其中的包。
+	// bubblewrap 后端以只读方式挂载这些目录，Docker 后端以只读方式挂载到容器的 /sandbox-lib/<序号>；
+	// 进程后端无法挂载，只读依赖目录的文件权限，应配合 RunAsUser 使用。
+	// 目录在创建执行器时检查，不存在或不是目录时所有 Python 执行都以 ErrorKindInternal 失败
+	PythonLibDirs []string
+
+	// KillGracePeriod 是超时或取消时发送 SIGTERM 后等待进程自行退出的时间，此后发送 SIGKILL。
+	// 为 0 时使用 2 秒，小于 0 时直接发送 SIGKILL。宽限期内写出的输出仍计入结果；
+	// 没有信号的平台（Windows）上进程总是被立即终止
+	KillGracePeriod time.Duration
+
+	// MaxMemoryBytes 通过 RLIMIT_AS 限制子进程的虚拟内存大小，0 表示不限制。
+	// 仅在 Linux 上生效，其他平台上为空操作。
+	// 注意 V8、Go 运行时等会预留大量虚拟地址空间，限制过小会导致其无法启动
+	MaxMemoryBytes int64
+	// MaxCPUSeconds 通过 RLIMIT_CPU 限制子进程消耗的CPU时间（秒），0 表示不限制。
+	// 与墙钟超时相互独立，仅在 Linux 上生效
+	MaxCPUSeconds int64
+	// MaxProcesses 通过 RLIMIT_NPROC 限制子进程所属用户可拥有的进程（线程）数，防止 fork 炸弹，
+	// 小于等于 0 表示不限制。该限制按真实用户统计其全部进程与线程（包括并发的其他执行和 Node.js、Go、Java
+	// 运行时自身的线程），对 root 无效，应配合 RunAsUser 使用专用的非特权用户并留出足够余量；仅在 Linux 上生效
+	MaxProcesses int64
+	// MaxOpenFiles 通过 RLIMIT_NOFILE 限制子进程可同时打开的文件描述符数（含 socket 与管道），
+	// 0 表示不限制，沿用服务进程自身的限制。解释器启动即需要若干描述符，不宜低于 64；仅在 Linux 上生效
+	MaxOpenFiles int64
+	// MaxCodeBytes 限制单次提交的源代码字节数，超出时立即以 ErrCodeTooLarge 失败，
+	// 不会创建临时文件或占用工作池令牌。0 表示使用默认值 1MB，负数表示不限制
+	MaxCodeBytes int
+	// MaxOutputBytes 限制 stdout 与 stderr 合计捕获的字节数，0 表示不限制。
+	// 超出后停止捕获并终止进程，结果的 Truncated 置为 true
+	MaxOutputBytes int64
+	// MaxOutputRate 大于 0 时限制 stdout 与 stderr 合计的平均输出速率（字节/秒），按 OutputRateWindow
+	// （为 0 时使用 1 秒）分段统计，任一段内的输出量超过 MaxOutputRate × OutputRateWindow 时立即终止进程，
+	// 以 ErrorKindOutputFlood 失败。用于比总量上限更早地拦截刷屏的死循环
+	MaxOutputRate    int64
+	OutputRateWindow time.Duration
+	// DenyNetwork 为 true 时在 Linux 上让子进程运行于新的网络命名空间，无法访问外部网络。
+	// 无法施加隔离的平台上执行会直接失败，而不是在有网络的情况下运行。
+	// Deno 本身默认不授予 --allow-net，不额外创建命名空间
+	DenyNetwork bool
+	// BlockDNS 为 true 时通过环境变量让子进程的域名解析失败，是无法创建网络命名空间的主机上较弱的替代手段：
+	// 只对经由 glibc getaddrinfo 解析的程序生效（Python、Ruby、Node.js 的 dns.lookup 与 http 模块、
+	// 使用 cgo 的 Go 程序等），/etc/hosts 中的名称仍可解析；自带 DNS 客户端的代码（如 Node.js 的 dns.resolve、
+	// 纯 Go 解析器）、直接使用 IP 地址的连接都不受影响，代码也可以自行修改这些变量。
+	// 它不能代替 DenyNetwork，应与出站防火墙一起使用
+	BlockDNS bool
+	// MaxPendingJobs 限制通过 Submit 提交、尚未结束的异步任务数，为 0 时使用 100
+	MaxPendingJobs int
+	// RewriteTempPaths 为 true 时把输出与错误信息中的临时代码文件路径替换为 "<snippet>.py" 之类的固定名称，
+	// 错误栈不再暴露服务器的临时目录，同一段代码的输出也不再因路径随机而变化
+	RewriteTempPaths bool
+	// CodeViaStdin 为 true 时 Python 与 Node.js 代码通过标准输入传给解释器（python -、node -），
+	// 不写临时文件，错误栈中的文件名为 "<stdin>"。请求设置了 ExecOptions.Stdin 时标准输入留给用户输入，
+	// 代码仍写入临时文件
+	CodeViaStdin bool
+	// TempDir 是代码文件、编译产物和临时工作目录的创建位置，例如专用的 tmpfs 挂载点；
+	// 为空时使用 os.TempDir()。目录必须已经存在
+	TempDir string
+	// TempFS 是创建与删除临时代码文件和临时目录所用的文件系统，为 nil 时使用 OSFS，见 TempFS
+	TempFS TempFS
+	// AllowPackages 为 true 时才接受 ExecOptions.Packages，否则指定了依赖的执行以 ErrorKindInvalidRequest 失败。
+	// 安装会访问网络、运行包索引中的代码（如 wheel 的入口脚本），只应在信任所用包索引的场景中开启
+	AllowPackages bool
+	// PackageCacheDir 是按依赖集合缓存的依赖环境的存放目录，见 ExecOptions.Packages，
+	// 为空时使用 TempDir 下的 sandbox-packages。以 RunAsUser 运行时该目录须对运行用户可读
+	PackageCacheDir string
+	// InstallTimeout 限制一次依赖安装的时间，与执行超时分开计算，为 0 时使用 5 分钟
+	InstallTimeout time.Duration
+
+	// LanguageTimeouts 按语言覆盖 Timeout，如为编译耗时较长的 "go"、"rust" 设置更长的默认超时，
+	// 小于等于 0 的值被忽略。单次执行的超时按以下优先级确定：ExecOptions.Timeout > LanguageTimeouts > Timeout
+	LanguageTimeouts map[string]time.Duration
+	// CompileTimeout 大于 0 时 Go、C、Rust、Java 的编译阶段单独计时：编译最多耗时 CompileTimeout，
+	// 不占用执行超时，超出时以 ErrorKindCompileError 失败；为 0 时编译与运行共用执行超时
+	CompileTimeout time.Duration
+
+	// CacheSize 大于 0 时启用结果缓存，按 (语言, 代码, 标准输入, 命令行参数, 环境变量, 依赖) 复用此前的结果，
+	// 最多保留 CacheSize 条；CacheTTL 为缓存条目的有效期，0 表示不过期
+	CacheSize int
+	CacheTTL  time.Duration
+
+	// PreludeCode 与 PostludeCode 按语言配置拼接在每次提交的代码前后的代码，例如设置资源限制、固定随机数种子，
+	// 调用方看不到拼接后的代码。错误栈与编译错误中指向代码文件的行号按前置代码的行数还原为用户代码中的行号。
+	// 多文件项目与会话不拼接；ExecuteReader 遇到配置了拼接代码的语言时先把代码读入内存
+	PreludeCode  map[string]string
+	PostludeCode map[string]string
+
+	// CompileCacheSize 大于 0 时启用编译缓存：Go、C、Rust、Java 按 (语言, 源代码, 编译器版本) 复用此前的编译产物，
+	// 跳过编译直接运行，最多保留 CompileCacheSize 份；CompileCacheTTL 为条目的有效期，0 表示不过期。
+	// 编译产物保存在临时目录下，Shutdown 时删除
+	CompileCacheSize int
+	CompileCacheTTL  time.Duration
+
+	// Locale 选择面向用户的消息语言，支持 "zh"（默认）和 "en"；
+	// Messages 按 MessageID 覆盖其中的模板，可用于接入自定义翻译
+	Locale   string
+	Messages Catalog
+
+	// QueueTimeout 控制工作池已满时的行为：0 表示一直等待（默认），大于 0 时最多等待该时长，
+	// 小于 0 时立即返回。等不到空闲令牌的执行以 ErrorKindBusy 失败，当前排队数见 Stats().Queued
+	QueueTimeout time.Duration
+
+	// PriorityAging 是 ExecOptions.Priority 每一级相当于的排队时长，默认 10 秒：优先级高一级的执行
+	// 排在比它早到不足该时长的执行之前；低优先级的执行等待超过优先级差乘以该时长后排到新来的执行之前，
+	// 不会被持续到来的高优先级执行饿死
+	PriorityAging time.Duration
+
+	// RateLimiter 非空时每次执行前按 ExecOptions.ClientID 检查频率，超出时立即以 ErrRateLimited 失败而不排队
+	RateLimiter RateLimiter
+
+	// PythonImportPolicy 非空时在运行 Python 代码前静态检查其导入语句，违规时以 ErrorKindPolicyViolation 失败
+	PythonImportPolicy *ImportPolicy
+
+	// RunAsUser 非空时子进程以该用户（用户名或数字UID）运行，RunAsGroup 覆盖其主组。
+	// 通常需要以 root 启动服务；工作目录与代码文件的属主会被改为该用户。
+	// RequireNonRoot 为 true 时拒绝以 root 身份执行代码，包括未设置 RunAsUser 而服务本身是 root 的情况
+	RunAsUser      string
+	RunAsGroup     string
+	RequireNonRoot bool
+
+	// EnvAllowlist 是子进程从父进程继承的环境变量名，为 nil 时使用 PATH、HOME、TMPDIR、LANG，
+	// 空切片表示不继承任何变量。InheritEnv 为 true 时所有执行都继承完整的父进程环境，
+	// 仅适用于执行可信代码的场景
+	EnvAllowlist []string
+	InheritEnv   bool
+
+	// 子进程的 PATH 默认只包含解释器（或编译产物）所在目录与系统的标准目录
+	// （/usr/local/bin、/usr/bin、/bin，Windows 上为 System32 与系统目录），代码无法按名称调用
+	// 服务进程 PATH 中的其他程序，EnvAllowlist 中的 PATH 因此不生效。InheritPath 为 true，
+	// 或 InheritEnv、ExecOptions.InheritEnv 继承完整环境时沿用父进程的 PATH；ExecOptions.Env 中的 PATH 总是优先。
+	// 不适用于 Docker 后端，容器使用镜像自身的 PATH
+	InheritPath bool
+
+	// Umask 非 0 时子进程以该 umask 运行，例如 0o077 使代码创建的文件只有运行用户可以访问；
+	// 为 0 时继承服务进程的 umask。通过 /bin/sh 设置 umask 后再执行解释器，不适用于 Windows 与 Docker 后端
+	Umask os.FileMode
+
+	// Lang 与 Timezone 是子进程的区域设置（同时写入 LANG 与 LC_ALL）和时区（TZ），默认 "C.UTF-8" 与 "UTC"，
+	// 使日期、数字等的格式化结果在不同机器上一致；它们覆盖从父进程继承的同名变量，ExecOptions.Env 可以再覆盖。
+	// InheritLocale 为 true 时不设置这几个变量，沿用基础环境中的值
+	Lang          string
+	Timezone      string
+	InheritLocale bool
+
+	// Backend 选择执行后端：BackendProcess（默认）直接在宿主机上运行解释器，
+	// BackendDocker 在一次性容器中运行，配置见 Docker；BackendBubblewrap 在 bwrap 沙箱中运行解释器，
+	// 配置见 Bwrap。未知的取值不会注册任何内置语言
+	Backend string
+	Docker  DockerConfig
+	Bwrap   BwrapConfig
+
+	// RuntimeRefreshInterval 大于 0 时在后台按该间隔调用 RefreshRuntimes，
+	// 发现启动后才安装或被卸载的运行时，直到 Shutdown
+	RuntimeRefreshInterval time.Duration
+
+	// OnEvent 在执行开始、结束、超时及被拒绝时调用，可用于接入日志或监控，为 nil 时不做任何事。
+	// 它会被多个执行并发调用且在执行路径上同步运行，实现应当线程安全并尽快返回
+	OnEvent func(ExecEvent)
+
+	// CircuitBreaker 非空时按语言熔断：连续多次因沙箱自身的故障失败的语言暂时直接拒绝执行，
+	// 避免故障期间每个请求都重复失败，见 CircuitBreakerConfig 与 CircuitState
+	CircuitBreaker *CircuitBreakerConfig
+
+	// Retry 非空时，执行因沙箱自身的临时故障（如创建进程时 EAGAIN）失败后按其重新执行，
+	// 每次重试重新排队并重新计算超时；用户代码的失败与超时从不重试
+	Retry *RetryPolicy
+
+	// TracerProvider 用于为每次执行及其排队、编译、启动、等待等阶段创建 OpenTelemetry span，
+	// 为 nil 时使用 otel.GetTracerProvider() 返回的全局 TracerProvider（默认不记录任何数据）
+	TracerProvider trace.TracerProvider
+}
+
+// ExecOptions 是单次执行的可选参数，零值表示使用执行器的默认行为
+type ExecOptions struct {
+	ID      string        // 执行ID，为空时自动生成；同一时刻不能有两个相同ID的执行
+	Timeout time.Duration // 覆盖执行器的默认超时时间
+	Stdin   string        // 传给程序的标准输入，写完后关闭
+
+	// Args 是传给程序的命令行参数，位于代码文件之后（Python 的 sys.argv[1:]、Node.js 的 process.argv.slice(2)）。
+	// 每个参数作为独立的 argv 项传递，不经过 shell 解析。SQL 忽略该字段
+	Args []string
+
+	// Priority 是排队等待工作池令牌时的优先级，默认 PriorityNormal；工作池有空闲令牌时不影响执行，
+	// 排队规则见 Config.PriorityAging
+	Priority Priority
+
+	// ClientID 标识发起执行的客户端，配置了 Config.RateLimiter 时按其限流，为空的ID同样计数
+	ClientID string
+
+	// PythonVersion 选择执行 Python 代码的解释器，如 "python3.11"，取值见 PythonVersions。
+	// 为空或不是已发现的版本时使用 Config.PythonPath
+	PythonVersion string
+
+	// Packages 是运行代码前安装的依赖，须开启 Config.AllowPackages，只支持 Python 与 Node.js 的子进程与 bubblewrap 后端。
+	// Python 以 pip 的需求格式（如 "requests"、"numpy>=1.26"）安装到虚拟环境中，只安装预编译的 wheel，
+	// 不构建源码包；Node.js 以 npm install 的格式（如 "lodash"、"zod@3"）安装到生成的项目目录中，
+	// 不运行依赖的安装脚本（--ignore-scripts），代码通过 NODE_PATH 以 require 加载。只接受包索引中的名称与版本约束，URL、本地路径与版本库地址以
+	// ErrorKindInvalidRequest 失败。依赖环境按依赖集合缓存，相同集合的后续执行直接复用。安装失败时以 ErrorKindInstallError 失败，
+	// 不运行代码。安装是获得工作池令牌后、运行代码前单独的阶段，与代码一样以 RunAsUser、在沙箱中并受资源限制运行，
+	// 只是可以访问网络（不受 DenyNetwork 与 BlockDNS 约束）；耗时不计入 Timeout 与 DurationMs，而由 Config.InstallTimeout 限制
+	Packages []string
+
+	// CombineOutput 为 true 时进程的 stdout 与 stderr 共用同一个管道，按写入顺序交错保存在 Output 中，
+	// Stderr 为空，失败时 Error 也不再包含标准错误的内容；流式输出时全部作为 stdout 发送
+	CombineOutput bool
+
+	// StripANSI 为 true 时从返回的 Output、Stderr 与 Error 中删除 ANSI 转义序列（颜色、光标控制等），
+	// 适合纯文本展示；实时输出（ExecuteStream 等）不受影响
+	StripANSI bool
+
+	// Env 是注入子进程的环境变量，与基础环境合并后生效
+	Env map[string]string
+	// InheritEnv 为 true 时子进程继承父进程的完整环境，
+	// 默认只继承 Config.EnvAllowlist 中的变量
+	InheritEnv bool
+
+	// WorkDir 是子进程的工作目录，不存在时自动创建；
+	// 源代码临时文件仍写在系统临时目录中，执行结束后删除
+	WorkDir string
+	// InputFiles 在代码运行前写入工作目录，代码可以按相对文件名读取；
+	// 未指定 WorkDir 时会为其创建临时工作目录
+	InputFiles []InputFile
+
+	// CollectFiles 非空时，执行结束后回收工作目录中新建或修改过、且匹配其中任一 glob 的文件，
+	// 使用 "*" 回收全部文件；未指定 WorkDir 时会创建临时工作目录
+	CollectFiles []string
+	// MaxCollectBytes 限制回收文件的总大小，为 0 时使用 10MB
+	MaxCollectBytes int64
+
+	// runTimeout 大于 0 表示编译阶段单独计时，runCompiled 以它限制编译产物的运行时间，由 run 填写
+	runTimeout time.Duration
+
+	// sourceFile 非空时直接执行该文件而不是将代码写入临时文件，用于多文件项目
+	sourceFile string
+
+	// install 为 true 表示 cmd 是安装 Packages 的包管理器，工作目录即依赖环境目录：
+	// 与用户代码一样切换运行用户、施加沙箱与资源限制，但保留网络访问
+	install bool
+
+	// packageEnv 是安装好 Packages 的依赖环境目录（Python 虚拟环境或 npm 项目），由 run 在执行前填写
+	packageEnv string
+
+	// netSandboxed 为 true 表示运行时自身已禁止网络访问（如 Deno），DenyNetwork 无需网络命名空间
+	netSandboxed bool
+
+	// pythonLib 为 true 表示执行的是 Python 代码，PythonLibDirs 加入 PYTHONPATH 并以只读方式挂载，由 run 填写
+	pythonLib bool
+
+	// rejected 非空时不运行代码，直接以该结果结束，用于 ExecuteReader 与 ExecuteProject 在代码运行前发现的失败
+	rejected *ExecutionResult
+
+	// noCache 为 true 时不读写结果缓存，用于需要实际运行每一次的 ExecuteBench
+	noCache bool
+
+	// admitted 为 true 表示调用方已通过 admit 登记了本次执行，execute 不再重复检查关闭状态
+	admitted bool
+
+	// probe 为 true 表示由 HealthCheck 发起的执行，不受限流且不读写缓存，确保真正运行代码
+	probe bool
+
+	// stdout 与 stderr 非空时，输出在写入结果缓冲区的同时实时写入这两个 Writer
+	stdout io.Writer
+	stderr io.Writer
+
+	// discardStdout 为 true 时 stdout 只写入 stdout Writer，不保存到结果的 Output 中
+	discardStdout bool
+}
+
+// InputFile 是执行前放入工作目录的输入文件，Name 必须是不含 .. 的相对路径
+type InputFile struct {
+	Name    string
+	Content []byte
 }
 
 // CodeExecutor 是代码执行器的主要结构体
 type CodeExecutor struct {
-	timeout         time.Duration
-	maxWorkers      int
-	workerPool      chan struct{}
-	nodejsAvailable bool
-	mu              sync.Mutex
+	timeout        time.Duration
+	pending        chan struct{} // Submit 提交的异步任务的配额
+	pythonPath     string
+	nodePath       string
+	npmPath        string
+	limits         resourceLimits
+	maxOutputBytes int64
+	maxOutputRate  int64
+	rateWindow     time.Duration
+	maxCodeBytes   int
+	denyNetwork    bool
+	blockDNS       bool
+	codeViaStdin   bool
+	rewritePaths   bool
+	tempDir        string        // 为空时使用系统临时目录
+	killGrace      time.Duration // 发送 SIGTERM 后等待进程退出的时间，为 0 时直接 SIGKILL
+	allowPackages  bool
+	packageDir     string // 为空时使用临时目录下的 sandbox-packages
+	installTimeout time.Duration
+	tempFS         TempFS
+	timeouts       map[string]time.Duration // Config.LanguageTimeouts 的副本
+	compileTimeout time.Duration
+	packageMu      sync.Mutex
+	packageLocks   map[string]*sync.Mutex // 依赖环境目录 -> 串行化其安装的锁
+	cache          *resultCache           // 未启用缓存时为 nil
+	compileCache   *compileCache          // 未启用编译缓存时为 nil
+	bwrap          *BwrapConfig           // 使用 bubblewrap 后端时非空
+	credential     *credential            // 未设置 RunAsUser 时为 nil
+	credentialErr  error                  // RunAsUser 无法解析时，每次执行都以该错误失败
+	pythonLibDirs  []string               // Config.PythonLibDirs 的绝对路径
+	pythonLibErr   error                  // PythonLibDirs 无效时，每次 Python 执行都以该错误失败
+	preludes       map[string]string      // Config.PreludeCode
+	postludes      map[string]string      // Config.PostludeCode
+	requireNonRoot bool
+	importPolicy   *ImportPolicy
+	rateLimiter    RateLimiter
+	retry          *RetryPolicy // Config.Retry 的副本
+	breakers       *breakers
+	envAllowlist   []string
+	lang           string // 子进程的 LANG 与 LC_ALL
+	timezone       string // 子进程的 TZ
+	inheritLocale  bool
+	inheritEnv     bool
+	inheritPath    bool
+	umask          os.FileMode
+	messages       Catalog // 面向用户的消息模板
+	onEvent        func(ExecEvent)
+	tracer         trace.Tracer
+	metrics        *metrics
+	queued         atomic.Int64 // 等待工作池令牌的执行数
+	queueTimeout   time.Duration
+	priorityAging  time.Duration // 每一级优先级相当于的排队时长，见 Config.PriorityAging
+	backend        string
+	refreshEvery   time.Duration // Config.RuntimeRefreshInterval，Restart 据此恢复后台刷新
+
+	warmMu     sync.Mutex
+	warm       map[string][]*warmProcess // 语言名到空闲预热进程的映射
+	warmTarget int                       // Warmup 要求每种语言保持的空闲进程数
+
+	mu            sync.Mutex
+	running       map[string]context.CancelFunc // 执行ID到取消函数的映射
+	maxWorkers    int                           // 最大并发执行数，可由 SetMaxWorkers 调整
+	activeWorkers int                           // 正在占用工作池令牌的执行数
+	poolChanged   chan struct{}                 // 令牌释放或上限变化时关闭并替换，用于唤醒等待者
+	waiters       waitQueue                     // 等待工作池令牌的执行，按优先级与排队时长排序
+	waiterSeq     uint64                        // 下一个等待者的入队序号
+	runners       map[string]Runner             // 语言名到 Runner 的注册表
+	runtimes      *runtimeInfo                  // 最近一次运行时探测的结果，通过 currentRuntimes 读取
+	closing       bool                          // 已调用 Shutdown，不再接受新的执行
+	closed        chan struct{}                 // Shutdown 首次调用时关闭，通知后台任务退出，Restart 时替换
+	inflight      *sync.WaitGroup               // 已接受、尚未结束的执行，Restart 时替换
+	languages     []string                      // 已注册的语言，按注册顺序
 }
 
-// NewCodeExecutor 创建一个新的代码执行器实例
+// NewCodeExecutor 创建一个新的代码执行器实例，timeout 以秒为单位，
+// timeout 与 maxWorkers 小于等于 0 时分别使用 30 秒与 CPU 核数。
+// 需要更多配置时使用 New 与 Option，或 NewCodeExecutorWithConfig
 func NewCodeExecutor(timeout int, maxWorkers int) *CodeExecutor {
+	return NewCodeExecutorWithConfig(Config{
+		Timeout:    time.Duration(timeout) * time.Second,
+		MaxWorkers: maxWorkers,
+	})
+}
+
+// NewCodeExecutorWithConfig 根据 config 创建一个新的代码执行器实例
+func NewCodeExecutorWithConfig(config Config) *CodeExecutor {
+	if config.Timeout <= 0 {
+		config.Timeout = defaultTimeout
+	}
+	if config.InstallTimeout <= 0 {
+		config.InstallTimeout = defaultInstallTimeout
+	}
+	if config.TempFS == nil {
+		config.TempFS = OSFS{}
+	}
+	if config.PriorityAging <= 0 {
+		config.PriorityAging = defaultPriorityAging
+	}
+	if config.KillGracePeriod == 0 {
+		config.KillGracePeriod = defaultKillGrace
+	}
+	if config.MaxWorkers <= 0 {
+		config.MaxWorkers = runtime.NumCPU()
+	}
+	if config.PythonPath == "" {
+		config.PythonPath = lookupInterpreter(pythonCandidates, checkPythonAvailable)
+	}
+	if config.NodePath == "" {
+		config.NodePath = lookupInterpreter(nodeCandidates, checkNodeJSAvailable)
+	}
+	if config.NpmPath == "" {
+		config.NpmPath = "npm"
+	}
+	if config.EnvAllowlist == nil {
+		config.EnvAllowlist = baseEnvKeys
+	}
+	if config.Lang == "" {
+		config.Lang = defaultLang
+	}
+	if config.Timezone == "" {
+		config.Timezone = defaultTimezone
+	}
+	if config.OutputRateWindow <= 0 {
+		config.OutputRateWindow = time.Second
+	}
+	if config.MaxCodeBytes == 0 {
+		config.MaxCodeBytes = defaultMaxCodeBytes
+	}
+	if config.MaxPendingJobs <= 0 {
+		config.MaxPendingJobs = defaultMaxPendingJobs
+	}
+
 	executor := &CodeExecutor{
-		timeout:         time.Duration(timeout) * time.Second,
-		maxWorkers:      maxWorkers,
-		workerPool:      make(chan struct{}, maxWorkers),
-		nodejsAvailable: checkNodeJSAvailable(),
+		timeout:        config.Timeout,
+		pending:        make(chan struct{}, config.MaxPendingJobs),
+		pythonPath:     config.PythonPath,
+		nodePath:       config.NodePath,
+		npmPath:        config.NpmPath,
+		maxOutputBytes: config.MaxOutputBytes,
+		maxOutputRate:  config.MaxOutputRate,
+		rateWindow:     config.OutputRateWindow,
+		maxCodeBytes:   config.MaxCodeBytes,
+		codeViaStdin:   config.CodeViaStdin,
+		rewritePaths:   config.RewriteTempPaths,
+		tempDir:        config.TempDir,
+		preludes:       config.PreludeCode,
+		postludes:      config.PostludeCode,
+		killGrace:      max(config.KillGracePeriod, 0),
+		allowPackages:  config.AllowPackages,
+		packageDir:     config.PackageCacheDir,
+		installTimeout: config.InstallTimeout,
+		tempFS:         config.TempFS,
+		timeouts:       maps.Clone(config.LanguageTimeouts),
+		compileTimeout: config.CompileTimeout,
+		denyNetwork:    config.DenyNetwork,
+		blockDNS:       config.BlockDNS,
+		running:        make(map[string]context.CancelFunc),
+		maxWorkers:     config.MaxWorkers,
+		poolChanged:    make(chan struct{}),
+		closed:         make(chan struct{}),
+		inflight:       &sync.WaitGroup{},
+		messages:       newCatalog(config.Locale, config.Messages),
+		onEvent:        config.OnEvent,
+		tracer:         newTracer(config.TracerProvider),
+		requireNonRoot: config.RequireNonRoot,
+		importPolicy:   config.PythonImportPolicy,
+		rateLimiter:    config.RateLimiter,
+		inheritEnv:     config.InheritEnv,
+		envAllowlist:   append([]string(nil), config.EnvAllowlist...),
+		inheritPath:    config.InheritPath,
+		umask:          config.Umask,
+		lang:           config.Lang,
+		timezone:       config.Timezone,
+		inheritLocale:  config.InheritLocale,
+		queueTimeout:   config.QueueTimeout,
+		priorityAging:  config.PriorityAging,
+		metrics:        newMetrics(),
+		limits: resourceLimits{
+			maxMemoryBytes: config.MaxMemoryBytes,
+			maxCPUSeconds:  config.MaxCPUSeconds,
+			maxProcesses:   config.MaxProcesses,
+			maxOpenFiles:   config.MaxOpenFiles,
+		},
+	}
+	executor.breakers = newBreakers(config.CircuitBreaker)
+	if config.Retry != nil {
+		retry := *config.Retry
+		executor.retry = &retry
+	}
+	executor.runtimes = probeRuntimes(config.PythonPath, config.NodePath)
+	if config.RunAsUser != "" {
+		executor.credential, executor.credentialErr = resolveCredential(config.RunAsUser, config.RunAsGroup)
+	}
+	if len(config.PythonLibDirs) > 0 {
+		executor.pythonLibDirs, executor.pythonLibErr = resolveLibDirs(config.PythonLibDirs)
+	}
+	executor.backend = config.Backend
+	switch config.Backend {
+	case "", BackendProcess:
+		executor.runners = executor.builtinRunners()
+	case BackendDocker:
+		executor.runners = executor.dockerRunners(config.Docker)
+	case BackendBubblewrap:
+		executor.runners = executor.bwrapRunners(config.Bwrap)
+	default:
+		executor.runners = map[string]Runner{}
+	}
+	for _, language := range supportedLanguages {
+		if _, ok := executor.runners[language]; ok {
+			executor.languages = append(executor.languages, language)
+		}
+	}
+	if config.CacheSize > 0 {
+		executor.cache = newResultCache(config.CacheSize, config.CacheTTL)
+	}
+	if config.CompileCacheSize > 0 {
+		executor.compileCache = newCompileCache(config.CompileCacheSize, config.CompileCacheTTL, executor.tempRoot())
+	}
+	if config.RuntimeRefreshInterval > 0 {
+		executor.refreshEvery = config.RuntimeRefreshInterval
+		go executor.pollRuntimes(config.RuntimeRefreshInterval, executor.closed)
 	}
 	return executor
 }
 
-// checkNodeJSAvailable 检查Node.js是否可用
-func checkNodeJSAvailable() bool {
-	cmd := exec.Command("node", "--version")
-	err := cmd.Run()
-	return err == nil
+// runtimeInfo 是一次运行时探测的结果，创建后只读，RefreshRuntimes 整体替换它
+type runtimeInfo struct {
+	pythonAvailable bool
+	pythonVersions  map[string]string // 发现的 Python 解释器名称到路径的映射
+	nodejsAvailable bool
+	goAvailable     bool
+	rubyAvailable   bool
+	bashPath        string // bash 可执行文件的绝对路径，为空表示不可用
+	tsAvailable     bool
+	tsNodeAvailable bool // 为 false 时回退到 tsc 编译后交给 node 执行
+	denoAvailable   bool
+	sqliteAvailable bool
+	wasmAvailable   bool
+	cCompiler       string // C 编译器的绝对路径，为空表示不可用
+	rustAvailable   bool
+	javaAvailable   bool              // javac 与 java 都可用
+	versions        map[string]string // 语言名及 Python 解释器名称到版本信息的映射
 }
 
-// runPythonCode 在进程中执行Python代码
-func runPythonCode(code string) ExecutionResult {
-	var stdout, stderr bytes.Buffer
+// probeRuntimes 探测各语言的运行时是否可用，并记录其版本信息
+func probeRuntimes(pythonPath string, nodePath string) *runtimeInfo {
+	info := &runtimeInfo{versions: make(map[string]string)}
+	probes := []struct {
+		language  string
+		available *bool
+		name      string
+		args      []string
+	}{
+		{"python3", &info.pythonAvailable, pythonPath, []string{"--version"}},
+		{"nodejs", &info.nodejsAvailable, nodePath, []string{"--version"}},
+		{"go", &info.goAvailable, "go", []string{"version"}},
+		{"ruby", &info.rubyAvailable, "ruby", []string{"--version"}},
+		{"deno", &info.denoAvailable, "deno", []string{"--version"}},
+		{"sql", &info.sqliteAvailable, "sqlite3", []string{"--version"}},
+		{"wasm", &info.wasmAvailable, "wasmtime", []string{"--version"}},
+		{"rust", &info.rustAvailable, "rustc", []string{"--version"}},
+	}
+	for _, probe := range probes {
+		var version string
+		version, *probe.available = runtimeVersion(probe.name, probe.args...)
+		if *probe.available {
+			info.versions[probe.language] = version
+		}
+	}
 
-	// 创建临时文件
-	tmpFile, err := os.CreateTemp("", "python-*.py")
+	info.bashPath = lookupBash()
+	if version, ok := runtimeVersion(info.bashPath, "--version"); ok {
+		info.versions["bash"] = version
+	}
+	if version, ok := javaAvailable(); ok {
+		info.javaAvailable = true
+		info.versions["java"] = version
+	}
+	info.cCompiler = lookupCCompiler()
+	if version, ok := runtimeVersion(info.cCompiler, "--version"); ok {
+		info.versions["c"] = version
+	}
+
+	// 优先使用 ts-node，缺失时需要 tsc 与 node 同时可用
+	tsVersion, ok := runtimeVersion("ts-node", "--version")
+	info.tsNodeAvailable = ok
+	if !ok && info.nodejsAvailable {
+		tsVersion, ok = runtimeVersion("tsc", "--version")
+	}
+	info.tsAvailable = ok
+	if ok {
+		info.versions["typescript"] = tsVersion
+	}
+
+	var pythonVersions map[string]string
+	info.pythonVersions, pythonVersions = discoverPythonVersions()
+	for name, version := range pythonVersions {
+		info.versions[name] = version
+	}
+	return info
+}
+
+// currentRuntimes 返回最近一次运行时探测的结果
+func (e *CodeExecutor) currentRuntimes() *runtimeInfo {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return e.runtimes
+}
+
+// runtimeVersion 运行 name args... 查询运行时版本，返回输出的第一行；命令无法运行时 ok 为 false
+func runtimeVersion(name string, args ...string) (version string, ok bool) {
+	if name == "" {
+		return "", false
+	}
+	output, err := exec.Command(name, args...).CombinedOutput()
 	if err != nil {
-		return ExecutionResult{
-			Success: false,
-			Error:   fmt.Sprintf("创建临时文件失败: %v", err),
+		return "", false
+	}
+	version, _, _ = strings.Cut(strings.TrimSpace(string(output)), "\n")
+	return strings.TrimSpace(version), true
+}
+
+// checkPythonAvailable 检查 pythonPath 指向的Python是否可用
+func checkPythonAvailable(pythonPath string) bool {
+	_, ok := runtimeVersion(pythonPath, "--version")
+	return ok
+}
+
+// checkNodeJSAvailable 检查 nodePath 指向的Node.js是否可用
+func checkNodeJSAvailable(nodePath string) bool {
+	_, ok := runtimeVersion(nodePath, "--version")
+	return ok
+}
+
+// lookupBash 在 PATH 中查找 bash，返回解析后的绝对路径，找不到时返回空字符串
+func lookupBash() string {
+	path, err := exec.LookPath("bash")
+	if err != nil {
+		return ""
+	}
+	return path
+}
+
+// lookupCCompiler 在 PATH 中依次查找 cc、gcc、clang，返回第一个找到的绝对路径，均未找到时返回空字符串
+func lookupCCompiler() string {
+	for _, name := range []string{"cc", "gcc", "clang"} {
+		if path, err := exec.LookPath(name); err == nil {
+			return path
 		}
 	}
-	defer os.Remove(tmpFile.Name())
+	return ""
+}
 
-	// 写入代码到临时文件
-	if _, err := tmpFile.WriteString(code); err != nil {
-		return ExecutionResult{
-			Success: false,
-			Error:   fmt.Sprintf("写入代码失败: %v", err),
+// runtimeVersionOf 返回 language 本次执行所用运行时的版本信息。Docker 后端的运行时位于镜像中，
+// 自定义 Runner 的版本无从得知，均返回空字符串
+func (e *CodeExecutor) runtimeVersionOf(language string, opts ExecOptions) string {
+	if e.backend == BackendDocker {
+		return ""
+	}
+	if _, ok := e.runner(language).(*builtinRunner); !ok {
+		return ""
+	}
+	versions := e.currentRuntimes().versions
+	if language == "python3" {
+		if version, ok := versions[opts.PythonVersion]; ok {
+			return version
 		}
 	}
+	return versions[language]
+}
+
+// exitCodeOf 返回 cmd 的退出码，进程未启动或被信号终止时返回 -1
+func exitCodeOf(cmd *exec.Cmd) int {
+	if cmd.ProcessState == nil {
+		return -1
+	}
+	return cmd.ProcessState.ExitCode()
+}
+
+// runSourceFile 将代码写入匹配 pattern 的临时文件，并以 name args... <文件> 的形式执行，
+// opts.sourceFile 非空时忽略 code 直接执行该文件。按 opts 设置子进程，ctx 到期时终止子进程
+func (e *CodeExecutor) runSourceFile(ctx context.Context, opts ExecOptions, pattern string, code string, name string, args ...string) ExecutionResult {
+	// 多文件项目的入口文件已经写入工作目录，直接执行
+	if opts.sourceFile != "" {
+		cmd := exec.CommandContext(ctx, name, programArgs(args, opts.sourceFile, opts)...)
+		return e.runUserCommand(ctx, cmd, opts)
+	}
+
+	// 创建临时文件
+	_, write := startPhase(ctx, "sandbox.write_code")
+	tmpFile, err := e.tempFS.CreateTemp(e.tempDir, pattern)
+	if err != nil {
+		endPhase(write, err)
+		return e.fail(ErrorKindInternal, MsgCreateTempFile, err)
+	}
+	defer e.removeTemp(tmpFile.Name())
+
+	// 写入代码到临时文件
+	if _, err := io.WriteString(tmpFile, code); err != nil {
+		tmpFile.Close()
+		endPhase(write, err)
+		return e.fail(ErrorKindInternal, MsgWriteCode, err)
+	}
 	tmpFile.Close()
+	endPhase(write, nil)
 
-	// 执行Python代码
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
+	// 执行代码
+	cmd := exec.CommandContext(ctx, name, programArgs(args, tmpFile.Name(), opts)...)
+	return e.rewriteTempPath(e.runUserCommand(ctx, cmd, opts), tmpFile.Name(), filepath.Ext(pattern))
+}
+
+// rewriteTempPath 在启用 RewriteTempPaths 时把结果输出中的临时文件路径 path 替换为 "<snippet>"+ext，
+// path 为空（代码不在临时文件中）时原样返回
+func (e *CodeExecutor) rewriteTempPath(result ExecutionResult, path string, ext string) ExecutionResult {
+	if !e.rewritePaths || path == "" {
+		return result
+	}
+	name := "<snippet>" + ext
+	result.Output = strings.ReplaceAll(result.Output, path, name)
+	result.Error = strings.ReplaceAll(result.Error, path, name)
+	result.Stderr = strings.ReplaceAll(result.Stderr, path, name)
+	return result
+}
+
+// programArgs 返回解释器的完整参数：interpreterArgs、代码文件 source，以及用户传给程序的 opts.Args
+func programArgs(interpreterArgs []string, source string, opts ExecOptions) []string {
+	args := append(append([]string(nil), interpreterArgs...), source)
+	return append(args, opts.Args...)
+}
+
+// runScript 执行能从标准输入读取脚本的解释器（参数 "-"）：启用 CodeViaStdin 且标准输入未被
+// opts.Stdin 占用时通过管道传入代码，否则与 runSourceFile 相同
+func (e *CodeExecutor) runScript(ctx context.Context, opts ExecOptions, pattern string, code string, name string, args ...string) ExecutionResult {
+	if !e.codeViaStdin || opts.Stdin != "" || opts.sourceFile != "" {
+		return e.runSourceFile(ctx, opts, pattern, code, name, args...)
+	}
+	cmd := exec.CommandContext(ctx, name, programArgs(args, "-", opts)...)
+	cmd.Stdin = strings.NewReader(code)
+	return e.runUserCommand(ctx, cmd, opts)
+}
+
+// runUserCommand 按单次执行的选项及执行器的资源限制设置运行用户代码的 cmd，并执行它
+func (e *CodeExecutor) runUserCommand(ctx context.Context, cmd *exec.Cmd, opts ExecOptions) ExecutionResult {
+	// 写完 Stdin 后管道会被关闭，读取方随即收到 EOF
+	if opts.Stdin != "" {
+		cmd.Stdin = strings.NewReader(opts.Stdin)
+	}
+	if err := e.prepareUserCommand(cmd, opts); err != nil {
+		return errorResultFrom(err)
+	}
 
-	cmd := exec.CommandContext(ctx, "python", tmpFile.Name())
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
+	result := e.runWithOutput(ctx, cmd, e.userOutput(cmd, opts))
+	return e.annotateLimits(result, cmd.ProcessState)
+}
 
-	err = cmd.Run()
+// prepareUserCommand 设置子进程的环境变量与工作目录，并施加运行用户、网络隔离和资源限制；
+// opts.install 为 true 时不隔离网络
+func (e *CodeExecutor) prepareUserCommand(cmd *exec.Cmd, opts ExecOptions) error {
+	cmd.Env = e.buildEnv(opts, filepath.Dir(cmd.Path))
+	cmd.Dir = opts.WorkDir
+	// 安装依赖的命令只读写依赖环境目录，即工作目录；它的参数中的路径位于依赖缓存目录下，不能当作代码文件改变属主
+	var codePaths, cached []string
+	if !opts.install {
+		var args []string
+		args, cached = e.compileCache.split(codeArgs(cmd, opts))
+		codePaths = tempPaths(e.tempRoot(), e.withoutPackagePaths(args))
+	}
+	if opts.pythonLib && e.pythonLibErr != nil {
+		return e.sandboxError(ErrorKindInternal, MsgPythonLibInvalid, e.pythonLibErr)
+	}
+	if err := e.dropPrivileges(cmd, opts.WorkDir, codePaths); err != nil {
+		return err
+	}
+	// bwrap 已取消网络命名空间，无需重复隔离
+	if e.bwrap != nil {
+		// 依赖环境可能位于沙箱内被 tmpfs 遮住的临时目录中，只读挂载但不改变属主
+		if opts.packageEnv != "" {
+			codePaths = append(codePaths, opts.packageEnv)
+		}
+		if opts.pythonLib {
+			codePaths = append(codePaths, e.pythonLibDirs...)
+		}
+		// 编译缓存的条目由所有执行共享，只挂载本次使用的条目，同样不改变属主
+		codePaths = append(codePaths, cached...)
+		wrapBubblewrap(cmd, *e.bwrap, opts.WorkDir, codePaths, opts.install)
+	} else if e.denyNetwork && !opts.netSandboxed && !opts.install {
+		if err := isolateNetwork(cmd); err != nil {
+			return e.sandboxError(ErrorKindInternal, MsgNetworkUnsupported)
+		}
+	}
+	if err := applyLimits(cmd, e.limits); err != nil {
+		return e.sandboxError(ErrorKindInternal, MsgLimitsUnavailable, err)
+	}
+	// umask 会被 prlimit、bwrap 与解释器继承，包装在最外层
+	if e.umask != 0 {
+		applyUmask(cmd, e.umask)
+	}
+	return nil
+}
+
+// codeArgs 返回 cmd 的可执行文件以及参数中属于解释器与代码文件的部分，去掉末尾的 opts.Args，
+// 避免把调用方传入的路径当作代码文件挂载进沙箱或修改其属主。编译型语言的可执行文件本身就是编译产物
+func codeArgs(cmd *exec.Cmd, opts ExecOptions) []string {
+	args := cmd.Args[1:]
+	if n := len(args) - len(opts.Args); n >= 0 && slices.Equal(args[n:], opts.Args) {
+		args = args[:n]
+	}
+	return append([]string{cmd.Path}, args...)
+}
+
+// runCommand 在独立进程组中运行 cmd 并收集 stdout/stderr，
+// maxOutput 大于 0 时输出合计超过该字节数即终止进程；
+// stdoutSink、stderrSink 非空时输出同时实时写入其中
+func (e *CodeExecutor) runCommand(ctx context.Context, cmd *exec.Cmd, maxOutput int64, stdoutSink, stderrSink io.Writer) ExecutionResult {
+	return e.runWithOutput(ctx, cmd, newCommandOutput(cmd, maxOutput, stdoutSink, stderrSink, true))
+}
+
+// runWithOutput 在独立进程组中运行 cmd，输出交给 output 收集
+func (e *CodeExecutor) runWithOutput(ctx context.Context, cmd *exec.Cmd, output *commandOutput) ExecutionResult {
+	cmd.Stdout = output.stdoutW
+	cmd.Stderr = output.stderrW
+	stop := setupProcessGroup(cmd, e.killGrace)
+	defer stop()
+	// 进程组被杀死后，残留的孙进程可能仍持有输出管道，避免 Wait 无限阻塞
+	cmd.WaitDelay = e.killGrace + time.Second
+
+	start := time.Now()
+	_, spawn := startPhase(ctx, "sandbox.spawn")
+	err := cmd.Start()
+	endPhase(spawn, err)
+	if err == nil {
+		_, wait := startPhase(ctx, "sandbox.wait")
+		err = cmd.Wait()
+		endPhase(wait, nil)
+	}
+	return e.commandResult(cmd, output, err, time.Since(start))
+}
+
+// commandOutput 收集一个子进程的 stdout/stderr，并按配额限制其大小
+type commandOutput struct {
+	stdout, stderr   bytes.Buffer
+	stdoutW, stderrW io.Writer
+	quota            *outputQuota
+	maxOutput        int64
+	rate             *rateMonitor // 配置了 MaxOutputRate 时非空
+}
+
+// newCommandOutput 创建 cmd 的输出收集器，输出超出 maxOutput 时终止 cmd。
+// keepStdout 为 false 时 stdout 只写入 stdoutSink，不保存到结果中
+func newCommandOutput(cmd *exec.Cmd, maxOutput int64, stdoutSink, stderrSink io.Writer, keepStdout bool) *commandOutput {
+	output := &commandOutput{maxOutput: maxOutput}
+	output.stdoutW, output.stderrW = &output.stdout, &output.stderr
+	if !keepStdout {
+		output.stdoutW = stdoutSink
+	} else if stdoutSink != nil {
+		output.stdoutW = io.MultiWriter(output.stdoutW, stdoutSink)
+	}
+	if stderrSink != nil {
+		output.stderrW = io.MultiWriter(output.stderrW, stderrSink)
+	}
+	if maxOutput > 0 {
+		output.quota = &outputQuota{
+			remaining: maxOutput,
+			onExceed:  func() { killProcessGroup(cmd) },
+		}
+		output.stdoutW = &cappedWriter{quota: output.quota, w: output.stdoutW}
+		output.stderrW = &cappedWriter{quota: output.quota, w: output.stderrW}
+	}
+	return output
+}
+
+// userOutput 创建运行用户代码的 cmd 的输出收集器：按 MaxOutputBytes 与 MaxOutputRate 限制输出，并同时写入 opts 指定的 Writer，
+// opts.CombineOutput 为 true 时 stderr 并入 stdout
+func (e *CodeExecutor) userOutput(cmd *exec.Cmd, opts ExecOptions) *commandOutput {
+	output := newCommandOutput(cmd, e.maxOutputBytes, opts.stdout, opts.stderr, !opts.discardStdout)
+	if e.maxOutputRate > 0 {
+		output.rate = newRateMonitor(e.maxOutputRate, e.rateWindow, func() { killProcessGroup(cmd) })
+		output.stdoutW = &rateWriter{monitor: output.rate, w: output.stdoutW}
+		output.stderrW = &rateWriter{monitor: output.rate, w: output.stderrW}
+	}
+	if opts.CombineOutput {
+		// Stdout 与 Stderr 是同一个 Writer 时 exec 只创建一个管道，写入顺序即进程的输出顺序
+		output.stderrW = output.stdoutW
+	}
+	return output
+}
+
+// cpuTime 返回进程消耗的用户态与内核态 CPU 时间之和（毫秒），进程未启动时返回 0
+func cpuTime(state *os.ProcessState) int64 {
+	if state == nil {
+		return 0
+	}
+	return (state.UserTime() + state.SystemTime()).Milliseconds()
+}
+
+// commandResult 根据 cmd 的退出状态与收集到的输出构造执行结果，err 是 Run 或 Wait 的返回值
+func (e *CodeExecutor) commandResult(cmd *exec.Cmd, output *commandOutput, err error, elapsed time.Duration) ExecutionResult {
+	duration := elapsed.Milliseconds()
+	stdout, stderr := output.stdout.String(), output.stderr.String()
+	signal := terminationSignal(cmd.ProcessState)
+	rss, cpu := peakRSS(cmd.ProcessState), cpuTime(cmd.ProcessState)
+	if output.rate != nil && output.rate.flooded() {
+		return ExecutionResult{
+			Success:     false,
+			Output:      stdout,
+			Error:       stderr + "\n" + e.msg(MsgOutputFlood, e.maxOutputRate, e.rateWindow.Seconds()),
+			Stderr:      stderr,
+			ErrorKind:   ErrorKindOutputFlood,
+			ExitCode:    exitCodeOf(cmd),
+			Signal:      signal,
+			DurationMs:  duration,
+			MaxRSSBytes: rss,
+			CPUTimeMs:   cpu,
+		}
+	}
+	if output.quota != nil && output.quota.truncated {
+		return ExecutionResult{
+			Success:     false,
+			Output:      stdout,
+			Error:       stderr + "\n" + e.msg(MsgOutputLimit, output.maxOutput),
+			Stderr:      stderr,
+			ErrorKind:   ErrorKindOutputLimit,
+			ExitCode:    exitCodeOf(cmd),
+			Signal:      signal,
+			DurationMs:  duration,
+			Truncated:   true,
+			MaxRSSBytes: rss,
+			CPUTimeMs:   cpu,
+		}
+	}
+	if err != nil && cmd.ProcessState == nil {
+		// 进程未能启动，例如解释器不存在或无法创建命名空间
+		return ExecutionResult{
+			Success:    false,
+			Error:      e.msg(MsgStartProcess, err),
+			ErrorKind:  ErrorKindInternal,
+			ExitCode:   -1,
+			DurationMs: duration,
+			transient:  isTransient(err),
+		}
+	}
 	if err != nil {
 		return ExecutionResult{
-			Success: false,
-			Output:  stdout.String(),
-			Error:   stderr.String(),
+			Success:     false,
+			Output:      stdout,
+			Error:       stderr,
+			Stderr:      stderr,
+			ErrorKind:   ErrorKindRuntimeError,
+			ExitCode:    exitCodeOf(cmd),
+			Signal:      signal,
+			DurationMs:  duration,
+			MaxRSSBytes: rss,
+			CPUTimeMs:   cpu,
 		}
 	}
 
 	return ExecutionResult{
-		Success: true,
-		Output:  stdout.String(),
-		Error:   "",
+		Success:     true,
+		Output:      stdout,
+		Error:       "",
+		Stderr:      stderr,
+		ExitCode:    0,
+		DurationMs:  duration,
+		MaxRSSBytes: rss,
+		CPUTimeMs:   cpu,
 	}
 }
 
-// runNodeJSCode 在进程中执行Node.js代码
-func runNodeJSCode(code string) ExecutionResult {
-	var stdout, stderr bytes.Buffer
+// runPythonCode 使用 opts.PythonVersion 选择的解释器执行Python代码，未选择时使用 e.pythonPath，
+// ctx 到期时终止子进程。配置了导入策略时先检查导入语句，违规时不运行代码。
+// 有空闲的预热进程时交给它执行，见 Warmup
+func (e *CodeExecutor) runPythonCode(ctx context.Context, code string, opts ExecOptions) ExecutionResult {
+	interpreter := e.pythonInterpreter(opts.PythonVersion)
+	if opts.packageEnv != "" {
+		interpreter = venvPython(opts.packageEnv)
+	}
+	if e.importPolicy != nil {
+		if rejected := e.checkPythonImports(ctx, interpreter, code, opts); rejected != nil {
+			return *rejected
+		}
+	}
+	if proc := e.takeWarm("python3", opts); proc != nil {
+		return e.runWarm(ctx, proc, code, opts)
+	}
+	return e.runScript(ctx, opts, "python-*.py", code, interpreter)
+}
 
-	// 创建临时文件
-	tmpFile, err := os.CreateTemp("", "nodejs-*.js")
+// runNodeJSCode 使用 e.nodePath 指定的解释器执行Node.js代码，ctx 到期时终止子进程。
+// 有空闲的预热进程时交给它执行，见 Warmup
+func (e *CodeExecutor) runNodeJSCode(ctx context.Context, code string, opts ExecOptions) ExecutionResult {
+	if opts.packageEnv != "" {
+		opts = withNodePath(opts)
+	}
+	if proc := e.takeWarm("nodejs", opts); proc != nil {
+		return e.runWarm(ctx, proc, code, opts)
+	}
+	return e.runScript(ctx, opts, "nodejs-*.js", code, e.nodePath)
+}
+
+// runGoCode 先用 go build 将代码编译到临时目录，再执行生成的二进制文件。
+// 编译在服务进程的环境中进行，以便使用 Go 的构建缓存
+func (e *CodeExecutor) runGoCode(ctx context.Context, code string, opts ExecOptions) ExecutionResult {
+	return e.runCompiled(ctx, code, opts, compiledLanguage{
+		language: "go",
+		name:     "Go",
+		source:   "main.go",
+		compile: func(ctx context.Context, src string, dir string) *exec.Cmd {
+			return exec.CommandContext(ctx, "go", "build", "-o", nativeBinary(dir), src)
+		},
+	})
+}
+
+// runCCode 先用探测到的 C 编译器将代码编译到临时目录，再执行生成的二进制文件
+func (e *CodeExecutor) runCCode(ctx context.Context, code string, opts ExecOptions) ExecutionResult {
+	return e.runCompiled(ctx, code, opts, compiledLanguage{
+		language: "c",
+		name:     "C",
+		source:   "main.c",
+		compile: func(ctx context.Context, src string, dir string) *exec.Cmd {
+			return exec.CommandContext(ctx, e.currentRuntimes().cCompiler, "-O2", "-o", nativeBinary(dir), src, "-lm")
+		},
+	})
+}
+
+// runRustCode 先用 rustc 将单文件代码编译到临时目录，再执行生成的二进制文件。
+// rustc 的中间文件写在临时目录中，不使用 Cargo
+func (e *CodeExecutor) runRustCode(ctx context.Context, code string, opts ExecOptions) ExecutionResult {
+	return e.runCompiled(ctx, code, opts, compiledLanguage{
+		language: "rust",
+		name:     "Rust",
+		source:   "main.rs",
+		compile: func(ctx context.Context, src string, dir string) *exec.Cmd {
+			cmd := exec.CommandContext(ctx, "rustc", "--edition", "2021", "-O", "-o", nativeBinary(dir), src)
+			cmd.Dir = dir
+			return cmd
+		},
+	})
+}
+
+// compiledLanguages 是通过 runCompiled 编译执行的内置语言，Config.CompileTimeout 只作用于它们
+var compiledLanguages = map[string]bool{"go": true, "c": true, "rust": true, "java": true}
+
+// compiledLanguage 描述一种由 runCompiled 先编译再执行的语言
+type compiledLanguage struct {
+	language string // 语言名，用于查找编译器版本
+	name     string // 用于提示信息与临时目录名
+	source   string // 单文件代码写入临时目录时使用的文件名
+	// compile 返回把源文件 src 编译到临时目录 dir 的命令
+	compile func(ctx context.Context, src string, dir string) *exec.Cmd
+	// run 返回执行编译产物的命令，不必包含 opts.Args；为 nil 时执行 compile 生成的 nativeBinary(dir)
+	run func(ctx context.Context, src string, dir string) *exec.Cmd
+}
+
+// nativeBinary 返回编译到临时目录 dir 的可执行文件路径
+func nativeBinary(dir string) string {
+	if runtime.GOOS == "windows" {
+		return filepath.Join(dir, "main.exe")
+	}
+	return filepath.Join(dir, "main")
+}
+
+// runCompiled 把代码写入临时目录中的 lang.source，用 lang.compile 返回的命令编译到同一目录后执行，
+// 源文件与编译产物随临时目录一起删除。编译在服务进程的环境中进行，不受资源限制；
+// 编译失败时不会运行代码，编译器的诊断信息写入 Error。启用 Config.CompileCacheSize 时，
+// 相同代码的编译产物移入编译缓存，之后的执行跳过编译直接运行
+func (e *CodeExecutor) runCompiled(ctx context.Context, code string, opts ExecOptions, lang compiledLanguage) ExecutionResult {
+	name, ext := lang.name, filepath.Ext(lang.source)
+
+	// 多文件项目的其他文件也参与编译，不使用编译缓存
+	var key string
+	if e.compileCache != nil && opts.sourceFile == "" {
+		key = compileCacheKey(lang, e.currentRuntimes().versions[lang.language], code)
+		if entry, release, ok := e.compileCache.get(key); ok {
+			defer release()
+			return e.runArtifacts(ctx, opts, lang, filepath.Join(entry.dir, lang.source), entry.dir, entry.src, 0)
+		}
+	}
+
+	dir, err := e.tempFS.MkdirTemp(e.tempDir, strings.ToLower(name)+"-*")
 	if err != nil {
-		return ExecutionResult{
-			Success: false,
-			Error:   fmt.Sprintf("创建临时文件失败: %v", err),
+		return e.fail(ErrorKindInternal, MsgCreateTempDir, err)
+	}
+	defer e.removeTemp(dir)
+
+	src, tempSrc := opts.sourceFile, ""
+	if src == "" {
+		src = filepath.Join(dir, lang.source)
+		tempSrc = src
+		if err := e.writeTempFile(src, []byte(code)); err != nil {
+			return e.fail(ErrorKindInternal, MsgWriteCode, err)
 		}
 	}
-	defer os.Remove(tmpFile.Name())
 
-	// 写入代码到临时文件
-	if _, err := tmpFile.WriteString(code); err != nil {
-		return ExecutionResult{
-			Success: false,
-			Error:   fmt.Sprintf("写入代码失败: %v", err),
+	// 编译单独计时时 ctx 的期限包含了编译与运行两个阶段，这里只限制编译，运行由 runArtifacts 限制
+	compileCtx := ctx
+	if opts.runTimeout > 0 {
+		var cancelCompile context.CancelFunc
+		compileCtx, cancelCompile = context.WithTimeout(ctx, e.compileTimeout)
+		defer cancelCompile()
+	}
+	compileCtx, compile := startPhase(compileCtx, "sandbox.compile")
+	compiled := e.runCommand(compileCtx, lang.compile(compileCtx, src, dir), 0, nil, nil)
+	endPhase(compile, nil)
+	if !compiled.Success {
+		message := e.msg(MsgCompileFailed, name) + ":\n" + compiled.Error
+		if ctx.Err() == nil && compileCtx.Err() == context.DeadlineExceeded {
+			message = e.msg(MsgCompileTimeout, e.compileTimeout.Round(time.Millisecond).Seconds()) + "\n" + message
 		}
+		return e.rewriteTempPath(ExecutionResult{
+			Success:    false,
+			Error:      message,
+			ErrorKind:  ErrorKindCompileError,
+			ExitCode:   compiled.ExitCode,
+			DurationMs: compiled.DurationMs,
+		}, tempSrc, ext)
 	}
-	tmpFile.Close()
 
-	// 执行Node.js代码
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
+	if key != "" {
+		if entry, release, ok := e.compileCache.put(key, dir, src); ok {
+			defer release()
+			dir, src = entry.dir, filepath.Join(entry.dir, lang.source)
+		}
+	}
+	return e.runArtifacts(ctx, opts, lang, src, dir, tempSrc, compiled.DurationMs)
+}
+
+// runArtifacts 执行 lang 编译到 dir 的产物，src 是编译所用的源文件。opts.runTimeout 大于 0 时
+// 以它限制运行时间；结果的 DurationMs 加上编译耗时 compileMs，输出中的 tempSrc 按 RewriteTempPaths 替换
+func (e *CodeExecutor) runArtifacts(ctx context.Context, opts ExecOptions, lang compiledLanguage, src string, dir string, tempSrc string, compileMs int64) ExecutionResult {
+	runCtx := ctx
+	if opts.runTimeout > 0 {
+		var cancelRun context.CancelFunc
+		runCtx, cancelRun = context.WithTimeout(ctx, opts.runTimeout)
+		defer cancelRun()
+	}
+	cmd := exec.CommandContext(runCtx, nativeBinary(dir))
+	if lang.run != nil {
+		cmd = lang.run(runCtx, src, dir)
+	}
+	cmd.Args = append(cmd.Args, opts.Args...)
+	result := e.runUserCommand(runCtx, cmd, opts)
+	if ctx.Err() == nil && runCtx.Err() == context.DeadlineExceeded {
+		result = e.runTimedOut(result, opts.runTimeout)
+	}
+	result.DurationMs += compileMs
+	return e.rewriteTempPath(result, tempSrc, filepath.Ext(lang.source))
+}
 
-	cmd := exec.CommandContext(ctx, "node", tmpFile.Name())
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
+// runRubyCode 在进程中执行Ruby代码，ctx 到期时终止子进程
+func (e *CodeExecutor) runRubyCode(ctx context.Context, code string, opts ExecOptions) ExecutionResult {
+	return e.runSourceFile(ctx, opts, "ruby-*.rb", code, "ruby")
+}
 
-	err = cmd.Run()
+// runBashCode 使用探测到的 bash 执行脚本。
+// 脚本文件由 TempFS.CreateTemp 以 0600 权限创建，解释器显式读取，无需可执行权限
+func (e *CodeExecutor) runBashCode(ctx context.Context, code string, opts ExecOptions) ExecutionResult {
+	return e.runSourceFile(ctx, opts, "bash-*.sh", code, e.currentRuntimes().bashPath)
+}
+
+// runTypeScriptCode 通过 ts-node 直接执行TypeScript代码
+func (e *CodeExecutor) runTypeScriptCode(ctx context.Context, code string, opts ExecOptions) ExecutionResult {
+	return e.runSourceFile(ctx, opts, "typescript-*.ts", code, "ts-node")
+}
+
+// runTypeScriptWithTsc 先用 tsc 将代码编译为JavaScript，再交给 e.nodePath 指定的 node 执行。
+// 编译失败时不会运行代码，tsc 的诊断信息（输出在 stdout 上）写入 Error
+func (e *CodeExecutor) runTypeScriptWithTsc(ctx context.Context, code string, opts ExecOptions) ExecutionResult {
+	dir, err := e.tempFS.MkdirTemp(e.tempDir, "typescript-*")
 	if err != nil {
-		return ExecutionResult{
-			Success: false,
-			Output:  stdout.String(),
-			Error:   stderr.String(),
+		return e.fail(ErrorKindInternal, MsgCreateTempDir, err)
+	}
+	defer e.removeTemp(dir)
+
+	// 单文件代码写入临时目录；多文件项目以工作目录为源码根目录，编译产物仍写入临时目录
+	src, rootDir, tempSrc := opts.sourceFile, opts.WorkDir, ""
+	if src == "" {
+		src, rootDir = filepath.Join(dir, "main.ts"), dir
+		tempSrc = src
+		if err := e.writeTempFile(src, []byte(code)); err != nil {
+			return e.fail(ErrorKindInternal, MsgWriteCode, err)
 		}
 	}
+	rel, err := filepath.Rel(rootDir, src)
+	if err != nil {
+		return e.fail(ErrorKindInternal, MsgResolveEntry, err)
+	}
 
-	return ExecutionResult{
-		Success: true,
-		Output:  stdout.String(),
-		Error:   "",
+	compileCtx, compile := startPhase(ctx, "sandbox.compile")
+	compiled := e.runCommand(compileCtx, exec.CommandContext(ctx, "tsc", "--outDir", dir, "--rootDir", rootDir, src), 0, nil, nil)
+	endPhase(compile, nil)
+	if !compiled.Success {
+		return e.rewriteTempPath(ExecutionResult{
+			Success:    false,
+			Error:      e.msg(MsgCompileFailed, "TypeScript") + ":\n" + compiled.Output + compiled.Error,
+			ErrorKind:  ErrorKindCompileError,
+			ExitCode:   compiled.ExitCode,
+			DurationMs: compiled.DurationMs,
+		}, tempSrc, ".ts")
+	}
+
+	js := filepath.Join(dir, strings.TrimSuffix(rel, filepath.Ext(rel))+".js")
+	cmd := exec.CommandContext(ctx, e.nodePath, programArgs(nil, js, opts)...)
+	result := e.runUserCommand(ctx, cmd, opts)
+	result.DurationMs += compiled.DurationMs
+	if tempSrc != "" {
+		result = e.rewriteTempPath(result, js, ".js")
 	}
+	return result
+}
+
+// runDenoCode 通过 deno run 执行JavaScript/TypeScript代码。
+// 不传递任何 --allow-* 参数，默认拒绝网络、文件系统、环境变量、子进程等全部权限；
+// 临时文件使用 .ts 扩展名，Deno 原生支持TypeScript，同时兼容纯JavaScript
+func (e *CodeExecutor) runDenoCode(ctx context.Context, code string, opts ExecOptions) ExecutionResult {
+	opts.netSandboxed = true
+	return e.runSourceFile(ctx, opts, "deno-*.ts", code, "deno", "run")
+}
+
+// runSQLCode 在 sqlite3 的内存数据库中执行SQL语句，查询结果以带表头的列格式输出。
+// 语句经标准输入传给 sqlite3，-bail 使其在第一条出错的语句处停止并以非零状态退出，
+// 因此 opts.Stdin 不适用于SQL
+func (e *CodeExecutor) runSQLCode(ctx context.Context, code string, opts ExecOptions) ExecutionResult {
+	cmd := exec.CommandContext(ctx, "sqlite3", "-bail", "-batch", "-header", "-column", ":memory:")
+	cmd.Stdin = strings.NewReader(code)
+	opts.Stdin = ""
+	return e.runUserCommand(ctx, cmd, opts)
 }
 
 // Execute 执行代码
 func (e *CodeExecutor) Execute(code string, language string) ExecutionResult {
-	// 获取工作池令牌
-	e.workerPool <- struct{}{}
-	defer func() { <-e.workerPool }()
+	return e.ExecuteContext(context.Background(), code, language)
+}
+
+// ExecuteContext 在 ctx 的控制下执行代码，ctx 的截止时间与执行器的默认超时以先到者为准，
+// ctx 被取消时立即终止子进程
+func (e *CodeExecutor) ExecuteContext(ctx context.Context, code string, language string) ExecutionResult {
+	return e.execute(ctx, code, language, ExecOptions{})
+}
+
+// ExecuteWithTimeout 使用本次调用指定的超时时间执行代码，timeout 为 0 时使用执行器的默认超时
+func (e *CodeExecutor) ExecuteWithTimeout(code string, language string, timeout time.Duration) ExecutionResult {
+	return e.ExecuteWithOptions(code, language, ExecOptions{Timeout: timeout})
+}
+
+// ExecuteWithStdin 执行代码并将 stdin 作为标准输入传给程序
+func (e *CodeExecutor) ExecuteWithStdin(code string, language string, stdin string) ExecutionResult {
+	return e.ExecuteWithOptions(code, language, ExecOptions{Stdin: stdin})
+}
+
+// ExecuteWithOptions 按 opts 指定的单次执行选项执行代码
+func (e *CodeExecutor) ExecuteWithOptions(code string, language string, opts ExecOptions) ExecutionResult {
+	return e.execute(context.Background(), code, language, opts)
+}
+
+// ExecuteContextWithOptions 在 ctx 的控制下按 opts 执行代码，兼具 ExecuteContext 与 ExecuteWithOptions 的行为
+func (e *CodeExecutor) ExecuteContextWithOptions(ctx context.Context, code string, language string, opts ExecOptions) ExecutionResult {
+	return e.execute(ctx, code, language, opts)
+}
 
-	ctx, cancel := context.WithTimeout(context.Background(), e.timeout)
+// defaultMaxCodeBytes 是 Config.MaxCodeBytes 未设置时允许的源代码大小
+const defaultMaxCodeBytes = 1 << 20
+
+// timeoutFor 返回 language 的执行超时：requested 大于 0 时使用它，其次是 Config.LanguageTimeouts 中的值，
+// 最后是执行器的默认超时
+func (e *CodeExecutor) timeoutFor(language string, requested time.Duration) time.Duration {
+	if requested > 0 {
+		return requested
+	}
+	if timeout := e.timeouts[language]; timeout > 0 {
+		return timeout
+	}
+	return e.timeout
+}
+
+// codeTooLarge 判断 size 字节的代码是否超出 MaxCodeBytes
+func (e *CodeExecutor) codeTooLarge(size int) bool {
+	return e.maxCodeBytes > 0 && size > e.maxCodeBytes
+}
+
+// unavailableError 返回 language 当前无法执行的原因，可以执行时返回 nil
+func (e *CodeExecutor) unavailableError(language string) error {
+	runner := e.runner(language)
+	if runner == nil {
+		return e.sandboxError(ErrorKindLanguageUnsupported, MsgLanguageUnsupported, language)
+	}
+	if !runner.Available() {
+		name := language
+		if builtin, ok := runner.(*builtinRunner); ok {
+			name = builtin.name
+		}
+		return e.sandboxError(ErrorKindRuntimeUnavailable, MsgRuntimeUnavailable, name)
+	}
+	return nil
+}
+
+// execute 为本次执行分配ID并登记取消函数，是各 Execute 方法的公共实现
+func (e *CodeExecutor) execute(parent context.Context, code string, language string, opts ExecOptions) (result ExecutionResult) {
+	parent, span := e.startExecution(parent, language)
+	cached := false
+	defer func() { endExecution(span, result, cached) }()
+
+	ctx, cancel := context.WithCancel(parent)
 	defer cancel()
 
-	resultChan := make(chan ExecutionResult, 1)
+	id := opts.ID
+	if id == "" {
+		id = newExecutionID()
+	}
+	opts.ID = id
+	if !opts.admitted {
+		if !e.admit() {
+			result := e.fail(ErrorKindShuttingDown, MsgShuttingDown)
+			result.ID = id
+			result.Language = language
+			e.finish(language, result, false)
+			return result
+		}
+		defer e.inflight.Done()
+	}
+	// 缓存命中同样计入频率，限流在查询缓存之前进行
+	if e.rateLimiter != nil && !opts.probe && !e.rateLimiter.Allow(opts.ClientID) {
+		result := e.fail(ErrorKindRateLimited, MsgRateLimited, opts.ClientID)
+		result.ID = id
+		result.Language = language
+		e.finish(language, result, false)
+		return result
+	}
+	if !e.track(id, cancel) {
+		result := e.fail(ErrorKindInvalidRequest, MsgDuplicateID, id)
+		result.ID = id
+		result.Language = language
+		e.finish(language, result, false)
+		return result
+	}
+	defer e.untrack(id)
 
-	go func() {
-		var result ExecutionResult
-		switch language {
-		case "python3":
-			result = runPythonCode(code)
-		case "nodejs":
-			if !e.nodejsAvailable {
-				result = ExecutionResult{
-					Success: false,
-					Error:   "Node.js未安装或不可用",
-				}
-			} else {
-				result = runNodeJSCode(code)
+	// 指定了工作目录、输入文件或实时输出的执行有副作用，不参与缓存
+	var key string
+	useCache := e.cache != nil && opts.WorkDir == "" && len(opts.InputFiles) == 0 && len(opts.CollectFiles) == 0 &&
+		opts.stdout == nil && opts.stderr == nil && !opts.probe && !opts.noCache && opts.rejected == nil
+	if useCache {
+		key = cacheKey(language, code, opts)
+		if result, ok := e.cache.get(key); ok {
+			result.ID = id
+			result.Attempts = 0
+			result.StartedAt, result.FinishedAt, result.QueueWaitMs = time.Time{}, time.Time{}, 0
+			cached = true
+			e.finish(language, result, true)
+			return result
+		}
+	}
+
+	for attempt := 1; ; attempt++ {
+		if ok, failures := e.breakers.allow(language); !ok {
+			result = e.fail(ErrorKindRuntimeUnavailable, MsgCircuitOpen, language, failures)
+			break
+		}
+		result = e.run(ctx, code, language, opts)
+		e.breakers.record(language, result)
+		result.Attempts = attempt
+		if !e.retry.shouldRetry(result, attempt) || !e.retry.wait(ctx, attempt) {
+			break
+		}
+	}
+	if opts.StripANSI {
+		result = stripANSI(result)
+	}
+	result = sanitizeUTF8(result)
+	result.ID = id
+	result.Language = language
+	result.ErrorPhase = phaseOf(result)
+	if result.ErrorPhase != PhaseSetup {
+		result.RuntimeVersion = e.runtimeVersionOf(language, opts)
+	}
+	e.finish(language, result, false)
+	if useCache && cacheable(result) {
+		e.cache.put(key, result)
+	}
+	return result
+}
+
+// run 在 parent 的基础上施加超时，占用一个工作池令牌执行代码
+func (e *CodeExecutor) run(parent context.Context, code string, language string, opts ExecOptions) (result ExecutionResult) {
+	if opts.rejected != nil {
+		return *opts.rejected
+	}
+	if e.codeTooLarge(len(code)) {
+		return e.fail(ErrorKindCodeTooLarge, MsgCodeTooLarge, e.maxCodeBytes)
+	}
+	if err := e.unavailableError(language); err != nil {
+		return errorResultFrom(err)
+	}
+	// 自定义 Runner 自行处理 Packages；内置语言先检查依赖，安装在获得工作池令牌后进行
+	_, builtin := e.runner(language).(*builtinRunner)
+	install := builtin && len(opts.Packages) > 0
+	if install {
+		if err := e.checkPackages(language, opts.Packages); err != nil {
+			return errorResultFrom(err)
+		}
+	}
+	opts.pythonLib = language == "python3"
+	if opts.sourceFile == "" {
+		if wrapped, offset := e.wrapCode(language, code); wrapped != code {
+			lines := codeLines(code)
+			defer func() { result = unwrapLines(result, language, wrapped, offset, lines) }()
+			code = wrapped
+		}
+	}
+
+	timeout := e.timeoutFor(language, opts.Timeout)
+	// 调用方的截止时间更早时以其为准，超时信息中报告实际生效的时长
+	if deadline, ok := parent.Deadline(); ok && time.Until(deadline) < timeout {
+		timeout = time.Until(deadline)
+	}
+	// 编译单独计时的语言在执行超时之外再留出 CompileTimeout，两个阶段的期限由 runCompiled 分别施加
+	limit := timeout
+	if _, builtin := e.runner(language).(*builtinRunner); builtin && e.compileTimeout > 0 &&
+		compiledLanguages[language] && e.backend != BackendDocker {
+		opts.runTimeout = timeout
+		limit += e.compileTimeout
+	}
+
+	workDir, err := e.prepareWorkDir(opts.WorkDir)
+	if err != nil {
+		return errorResultFrom(err)
+	}
+	opts.WorkDir = workDir
+
+	// 未指定工作目录时为输入、输出文件创建一个临时工作目录，执行结束后删除
+	if (len(opts.InputFiles) > 0 || len(opts.CollectFiles) > 0) && opts.WorkDir == "" {
+		dir, err := e.tempFS.MkdirTemp(e.tempDir, "workspace-*")
+		if err != nil {
+			return e.fail(ErrorKindInternal, MsgCreateTempDir, err)
+		}
+		defer e.removeTemp(dir)
+		opts.WorkDir = dir
+	}
+	for _, file := range opts.InputFiles {
+		if err := e.writeWorkspaceFile(opts.WorkDir, file.Name, file.Content); err != nil {
+			return errorResultFrom(err)
+		}
+	}
+
+	var before map[string]fileStamp
+	if len(opts.CollectFiles) > 0 {
+		if before, err = snapshotDir(opts.WorkDir); err != nil {
+			return e.fail(ErrorKindInternal, MsgReadWorkDir, err)
+		}
+		defer func() {
+			maxBytes := opts.MaxCollectBytes
+			if maxBytes <= 0 {
+				maxBytes = defaultMaxCollectBytes
 			}
-		default:
-			result = ExecutionResult{
-				Success: false,
-				Error:   fmt.Sprintf("不支持的语言: %s", language),
+			files, truncated, err := collectFiles(opts.WorkDir, before, opts.CollectFiles, maxBytes)
+			if err != nil {
+				result.Error += "\n" + e.msg(MsgCollectFiles, err)
 			}
+			result.Files, result.FilesTruncated = files, truncated
+		}()
+	}
+
+	// 获取工作池令牌，等待期间调用方取消则直接返回
+	e.queued.Add(1)
+	_, queue := startPhase(parent, "sandbox.queue")
+	queuedAt := time.Now()
+	err = e.acquireWorker(parent, opts.Priority)
+	queueWait := time.Since(queuedAt)
+	endPhase(queue, nil)
+	e.queued.Add(-1)
+	defer func() { result.QueueWaitMs = queueWait.Milliseconds() }()
+	if errors.Is(err, errPoolBusy) {
+		return e.fail(ErrorKindBusy, MsgBusy)
+	}
+	if err != nil {
+		return e.canceledResult(parent, timeout, 0)
+	}
+	defer e.releaseWorker()
+	if install {
+		dir, failed := e.installPackages(parent, language, opts)
+		if failed != nil {
+			return *failed
 		}
-		resultChan <- result
+		opts.packageEnv = dir
+	}
+	e.emit(ExecEvent{Type: EventStarted, ID: opts.ID, Language: language})
+
+	ctx, cancel := context.WithTimeout(parent, limit)
+	defer cancel()
+
+	// 输出同时写入这两个缓冲区，超时后进程仍未退出时也能返回已产生的部分
+	var partialStdout, partialStderr lockedBuffer
+	if !opts.discardStdout {
+		opts.stdout = teeWriter(&partialStdout, opts.stdout)
+	}
+	opts.stderr = teeWriter(&partialStderr, opts.stderr)
+
+	start := time.Now()
+	defer func() { result.StartedAt, result.FinishedAt = start, time.Now() }()
+	resultChan := make(chan ExecutionResult, 1)
+
+	runner := e.runner(language)
+	go func() {
+		// Runner 在独立的 goroutine 中运行，panic 无法被调用方捕获，会使整个进程退出，
+		// 本次执行的临时目录与工作池令牌也得不到释放。这里将其转换为内部错误
+		defer func() {
+			if r := recover(); r != nil {
+				resultChan <- e.fail(ErrorKindInternal, MsgRunnerPanic, language, r)
+			}
+		}()
+		resultChan <- runner.Run(ctx, code, opts)
 	}()
 
 	select {
 	case result := <-resultChan:
 		return result
 	case <-ctx.Done():
-		return ExecutionResult{
-			Success: false,
-			Error:   fmt.Sprintf("代码执行超时 (>%d秒)", int(e.timeout.Seconds())),
+		// 进程组随 ctx 结束收到 SIGTERM，等它在宽限期内退出，写出的输出与实际的退出状态一并返回
+		finished := ExecutionResult{ExitCode: -1, Signal: killSignal}
+		select {
+		case finished = <-resultChan:
+		case <-time.After(e.killGrace + time.Second):
 		}
+		result := e.canceledResult(parent, timeout, time.Since(start))
+		result.ExitCode, result.Signal = finished.ExitCode, finished.Signal
+		result.MaxRSSBytes, result.CPUTimeMs = finished.MaxRSSBytes, finished.CPUTimeMs
+		result.Output = partialStdout.String()
+		result.Stderr = partialStderr.String()
+		if result.Stderr != "" {
+			result.Error = result.Stderr + "\n" + result.Error
+		}
+		return result
+	}
+}
+
+// runTimedOut 把编译产物超过 timeout 后被终止的 finished 转换为超时结果，保留已产生的输出与退出状态
+func (e *CodeExecutor) runTimedOut(finished ExecutionResult, timeout time.Duration) ExecutionResult {
+	result := e.fail(ErrorKindTimeout, MsgTimeout, timeout.Round(time.Millisecond).Seconds())
+	result.ExitCode, result.Signal = finished.ExitCode, finished.Signal
+	result.MaxRSSBytes, result.CPUTimeMs = finished.MaxRSSBytes, finished.CPUTimeMs
+	result.Output, result.Stderr, result.Truncated = finished.Output, finished.Stderr, finished.Truncated
+	result.DurationMs = finished.DurationMs
+	if result.Stderr != "" {
+		result.Error = result.Stderr + "\n" + result.Error
 	}
+	return result
 }
 
-// Shutdown 关闭执行器
-func (e *CodeExecutor) Shutdown() {
-	// 等待所有工作完成
-	for i := 0; i < e.maxWorkers; i++ {
-		e.workerPool <- struct{}{}
+// canceledResult 构造执行被中断时的结果：调用方主动取消时报告取消，否则报告超时
+func (e *CodeExecutor) canceledResult(parent context.Context, timeout time.Duration, elapsed time.Duration) ExecutionResult {
+	result := e.fail(ErrorKindTimeout, MsgTimeout, timeout.Round(time.Millisecond).Seconds())
+	if errors.Is(parent.Err(), context.Canceled) {
+		result = e.fail(ErrorKindCanceled, MsgCanceled)
 	}
+	result.DurationMs = elapsed.Milliseconds()
+	return result
 }
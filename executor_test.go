@@ -0,0 +1,160 @@
+package sandbox
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		name        string
+		code        string
+		wantSuccess bool
+		wantCode    int
+		wantSignal  string
+	}{
+		{"success", `print("ok")`, true, 0, ""},
+		{"sys.exit(3)", "import sys\nsys.exit(3)", false, 3, ""},
+		{"uncaught exception", `raise ValueError("boom")`, false, 1, ""},
+		{"killed by signal", "import os, signal\nos.kill(os.getpid(), signal.SIGKILL)", false, -1, "SIGKILL"},
+	}
+	e := newTestExecutor(t, "python3", Config{})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.wantSignal != "" && runtime.GOOS == "windows" {
+				t.Skip("Windows 上没有信号")
+			}
+			result := e.Execute(tt.code, "python3")
+			if result.Success != tt.wantSuccess {
+				t.Fatalf("Success = %v，期望 %v（%s）", result.Success, tt.wantSuccess, result.Error)
+			}
+			if result.ExitCode != tt.wantCode {
+				t.Errorf("ExitCode = %d，期望 %d", result.ExitCode, tt.wantCode)
+			}
+			if result.Signal != tt.wantSignal {
+				t.Errorf("Signal = %q，期望 %q", result.Signal, tt.wantSignal)
+			}
+		})
+	}
+}
+
+func TestStderrKeptOnSuccess(t *testing.T) {
+	tests := []struct {
+		name       string
+		code       string
+		opts       ExecOptions
+		wantOutput string
+		wantStderr string
+	}{
+		{"stderr only", `import sys; print("warn", file=sys.stderr)`, ExecOptions{}, "", "warn\n"},
+		{"stdout and stderr", "import sys\nprint(\"out\")\nprint(\"warn\", file=sys.stderr)", ExecOptions{}, "out\n", "warn\n"},
+		{"combined output", "import sys\nprint(\"out\", flush=True)\nprint(\"warn\", file=sys.stderr)",
+			ExecOptions{CombineOutput: true}, "out\nwarn\n", ""},
+	}
+	e := newTestExecutor(t, "python3", Config{})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := e.ExecuteWithOptions(tt.code, "python3", tt.opts)
+			if !result.Success {
+				t.Fatalf("执行失败: %s", result.Error)
+			}
+			if result.Output != tt.wantOutput {
+				t.Errorf("Output = %q，期望 %q", result.Output, tt.wantOutput)
+			}
+			if result.Stderr != tt.wantStderr {
+				t.Errorf("Stderr = %q，期望 %q", result.Stderr, tt.wantStderr)
+			}
+			if result.Error != "" {
+				t.Errorf("成功时 Error = %q，期望为空", result.Error)
+			}
+		})
+	}
+}
+
+// paddedCode 返回恰好 size 字节、运行时打印 ok 的 Python 代码
+func paddedCode(size int) string {
+	const code = "print('ok')\n#"
+	return code + strings.Repeat("x", size-len(code))
+}
+
+func TestMaxCodeBytesBoundary(t *testing.T) {
+	tests := []struct {
+		name     string
+		maxBytes int
+		size     int
+		wantErr  bool
+	}{
+		{"below limit", 64, 63, false},
+		{"at limit", 64, 64, false},
+		{"one byte over", 64, 65, true},
+		{"default at limit", 0, defaultMaxCodeBytes, false},
+		{"default one byte over", 0, defaultMaxCodeBytes + 1, true},
+		{"unlimited", -1, 2 * defaultMaxCodeBytes, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			e := newTestExecutor(t, "python3", Config{MaxCodeBytes: tt.maxBytes, TempDir: dir})
+			result := e.Execute(paddedCode(tt.size), "python3")
+			if !tt.wantErr {
+				if !result.Success || result.Output != "ok\n" {
+					t.Fatalf("执行失败: %s", result.Error)
+				}
+				return
+			}
+			if !errors.Is(result.Err(), ErrCodeTooLarge) {
+				t.Fatalf("错误 = %v，期望 ErrCodeTooLarge", result.Err())
+			}
+			if !result.StartedAt.IsZero() {
+				t.Error("超出限制的代码不应进入运行")
+			}
+			if entries, _ := os.ReadDir(dir); len(entries) > 0 {
+				t.Errorf("超出限制时不应创建临时文件，临时目录中有 %d 项", len(entries))
+			}
+		})
+	}
+}
+
+func TestArgsPassthrough(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{"plain", []string{"one", "two"}},
+		{"shell metacharacters", []string{"with space", "$(id)", "; rm -rf /", "'quoted'", `"double"`, "--flag=value"}},
+		{"empty argument", []string{""}},
+		{"no arguments", nil},
+	}
+	languages := []struct {
+		language string
+		code     string
+	}{
+		{"python3", "import json, sys; print(json.dumps(sys.argv[1:]))"},
+		{"nodejs", "console.log(JSON.stringify(process.argv.slice(2)))"},
+	}
+	for _, lang := range languages {
+		t.Run(lang.language, func(t *testing.T) {
+			e := newTestExecutor(t, lang.language, Config{})
+			for _, tt := range tests {
+				t.Run(tt.name, func(t *testing.T) {
+					result := e.ExecuteWithOptions(lang.code, lang.language, ExecOptions{Args: tt.args})
+					if !result.Success {
+						t.Fatalf("执行失败: %s", result.Error)
+					}
+					var got []string
+					if err := json.Unmarshal([]byte(result.Output), &got); err != nil {
+						t.Fatalf("无法解析输出 %q: %v", result.Output, err)
+					}
+					if !slices.Equal(got, tt.args) {
+						t.Errorf("程序收到的参数 %q，期望 %q", got, tt.args)
+					}
+				})
+			}
+		})
+	}
+}
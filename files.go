@@ -0,0 +1,107 @@
+package sandbox
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+)
+
+// defaultMaxCollectBytes 是 ExecOptions.MaxCollectBytes 未设置时回收文件的总大小上限
+const defaultMaxCollectBytes = 10 << 20
+
+// OutputFile 是执行过程中在工作目录下新建或修改的文件
+type OutputFile struct {
+	Name    string `json:"name"`    // 相对工作目录的路径，使用 / 分隔
+	Content []byte `json:"content"` // JSON 中以 base64 编码
+}
+
+// fileStamp 用于判断文件在执行期间是否被修改
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// snapshotDir 记录 dir 下所有普通文件的修改时间和大小
+func snapshotDir(dir string) (map[string]fileStamp, error) {
+	stamps := make(map[string]fileStamp)
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(dir, p)
+		stamps[filepath.ToSlash(rel)] = fileStamp{modTime: info.ModTime(), size: info.Size()}
+		return nil
+	})
+	return stamps, err
+}
+
+// collectFiles 读取 dir 下相对 before 新建或修改过、且匹配 patterns 之一的普通文件。
+// patterns 按 path.Match 语法匹配相对路径或文件名，为空表示匹配全部；
+// 符号链接会被忽略，避免读取工作目录之外的文件。
+// 文件总大小超过 maxBytes 时跳过放不下的文件，并返回 truncated 为 true
+func collectFiles(dir string, before map[string]fileStamp, patterns []string, maxBytes int64) (files []OutputFile, truncated bool, err error) {
+	remaining := maxBytes
+	err = filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(dir, p)
+		name := filepath.ToSlash(rel)
+		if stamp, ok := before[name]; ok && stamp.modTime.Equal(info.ModTime()) && stamp.size == info.Size() {
+			return nil
+		}
+		if !matchAny(patterns, name) {
+			return nil
+		}
+		if info.Size() > remaining {
+			truncated = true
+			return nil
+		}
+
+		f, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		// 文件可能在 stat 之后继续增长，读取时同样限制大小
+		content, err := io.ReadAll(io.LimitReader(f, remaining+1))
+		if err != nil {
+			return err
+		}
+		if int64(len(content)) > remaining {
+			truncated = true
+			return nil
+		}
+		remaining -= int64(len(content))
+		files = append(files, OutputFile{Name: name, Content: content})
+		return nil
+	})
+	return files, truncated, err
+}
+
+// matchAny 判断相对路径 name 或其文件名是否匹配 patterns 中的任意一个
+func matchAny(patterns []string, name string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+		if ok, _ := path.Match(pattern, path.Base(name)); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,101 @@
+package sandbox
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// healthCheckTimeout 是健康检查中单个语言的超时时间，Go 首次编译需要较长时间
+const healthCheckTimeout = 10 * time.Second
+
+// healthProbes 是各内置语言用于健康检查的代码，运行后应输出 "1"
+var healthProbes = map[string]string{
+	"python3":    "print(1)",
+	"nodejs":     "console.log(1)",
+	"go":         "package main\n\nimport \"fmt\"\n\nfunc main() {\n\tfmt.Println(1)\n}\n",
+	"c":          "#include <stdio.h>\n\nint main(void) {\n\tputs(\"1\");\n\treturn 0;\n}\n",
+	"rust":       "fn main() {\n    println!(\"1\");\n}\n",
+	"java":       "public class Main {\n    public static void main(String[] args) {\n        System.out.println(1);\n    }\n}\n",
+	"ruby":       "puts 1",
+	"bash":       "echo 1",
+	"typescript": "console.log(1)",
+	"deno":       "console.log(1)",
+	"sql":        ".headers off\nSELECT 1;",
+	// 调用 WASI fd_write 向标准输出写 "1\n" 的最小模块
+	"wasm": "AGFzbQEAAAABDAJgBH9/f38Bf2AAAAIjARZ3YXNpX3NuYXBzaG90X3ByZXZpZXcxCGZkX3dyaXRlAAADAgEBBQMBAAEHEwIGbWVtb3J5AgAGX3N0YXJ0AAEKDwENAEEBQQBBAUEUEAAaCwsQAQBBAAsKCAAAAAIAAAAxCg==",
+}
+
+// HealthError 由 HealthCheck 返回，Degraded 记录未通过检查的语言及原因
+type HealthError struct {
+	Degraded map[string]string
+	message  string
+}
+
+func (e *HealthError) Error() string {
+	return e.message
+}
+
+// HealthCheck 在每种可用的内置语言中实际运行一段输出 "1" 的代码，确认完整的执行路径
+// （临时文件、资源限制、后端等）能在时限内正常工作，可用作就绪探针。
+// 检查并发进行且不读写结果缓存、不受限流；未安装的运行时不参与检查，自定义 Runner 也不检查。
+// 全部通过时返回 nil，否则返回 *HealthError；没有任何可用语言时同样视为不健康
+func (e *CodeExecutor) HealthCheck(ctx context.Context) error {
+	var languages []string
+	for _, language := range e.AvailableLanguages() {
+		if _, ok := healthProbes[language]; ok {
+			if _, builtin := e.runner(language).(*builtinRunner); builtin {
+				languages = append(languages, language)
+			}
+		}
+	}
+	if len(languages) == 0 {
+		return &HealthError{message: e.msg(MsgNoRuntimeAvailable)}
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	degraded := make(map[string]string)
+	for _, language := range languages {
+		wg.Add(1)
+		go func(language string) {
+			defer wg.Done()
+			result := e.execute(ctx, healthProbes[language], language, ExecOptions{Timeout: healthCheckTimeout, probe: true})
+			var reason string
+			switch {
+			case !result.Success:
+				reason = strings.TrimSpace(result.Error)
+				if reason == "" {
+					reason = string(result.ErrorKind)
+				}
+			case strings.TrimSpace(result.Output) != "1":
+				reason = e.msg(MsgUnexpectedOutput, result.Output)
+			default:
+				return
+			}
+			mu.Lock()
+			degraded[language] = reason
+			mu.Unlock()
+		}(language)
+	}
+	wg.Wait()
+	if len(degraded) == 0 {
+		return nil
+	}
+
+	names := make([]string, 0, len(degraded))
+	for language := range degraded {
+		names = append(names, language)
+	}
+	sort.Strings(names)
+	details := make([]string, len(names))
+	for i, language := range names {
+		details[i] = language + ": " + degraded[language]
+	}
+	return &HealthError{
+		Degraded: degraded,
+		message:  e.msg(MsgHealthCheckFailed, strings.Join(details, "; ")),
+	}
+}
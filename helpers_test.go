@@ -0,0 +1,20 @@
+package sandbox
+
+import (
+	"context"
+	"testing"
+)
+
+// systemPython 是以其他用户运行代码的测试使用的解释器，服务进程用户私有目录中的解释器对其他用户不可执行
+const systemPython = "/usr/bin/python3"
+
+// newTestExecutor 创建测试用的执行器，测试结束时关闭；language 的运行时不可用时跳过测试
+func newTestExecutor(t *testing.T, language string, config Config) *CodeExecutor {
+	t.Helper()
+	e := NewCodeExecutorWithConfig(config)
+	t.Cleanup(func() { e.Shutdown(context.Background()) })
+	if !e.IsLanguageAvailable(language) {
+		t.Skipf("%s 的运行时不可用", language)
+	}
+	return e
+}
@@ -0,0 +1,76 @@
+package sandbox
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// maxRequestBytes 限制 Handler 读取的请求体大小
+const maxRequestBytes = 16 << 20
+
+// httpRequest 是 Handler 接受的请求体
+type httpRequest struct {
+	Code     string  `json:"code"`
+	Language string  `json:"language"`
+	Stdin    string  `json:"stdin"`
+	Timeout  float64 `json:"timeout"` // 超时秒数，为 0 时使用执行器的默认超时
+}
+
+// Handler 返回执行代码的 http.Handler：接受 POST 的 JSON 请求 {code, language, stdin, timeout}，
+// 其中 timeout 为秒数，以 JSON 返回 ExecutionResult。执行在请求的 context 下进行，客户端断开连接时终止执行。
+//
+// 代码本身的失败（编译错误、运行时错误、触发资源限制等）仍返回 200，由结果中的 error_kind 区分；
+// 请求无法执行时按错误分类返回状态码：参数非法或不支持的语言为 400，超时为 408，
+// 工作池已满或被限流为 429，运行时不可用或执行器正在关闭为 503，沙箱内部错误为 500
+func Handler(e *CodeExecutor) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			writeJSON(w, http.StatusMethodNotAllowed, e.fail(ErrorKindInvalidRequest, MsgMethodNotAllowed, r.Method))
+			return
+		}
+
+		var req httpRequest
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBytes)).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, e.fail(ErrorKindInvalidRequest, MsgInvalidRequestBody, err))
+			return
+		}
+		if req.Timeout < 0 {
+			writeJSON(w, http.StatusBadRequest, e.fail(ErrorKindInvalidRequest, MsgInvalidTimeout, req.Timeout))
+			return
+		}
+
+		opts := ExecOptions{Stdin: req.Stdin, Timeout: time.Duration(req.Timeout * float64(time.Second))}
+		result := e.execute(r.Context(), req.Code, req.Language, opts)
+		writeJSON(w, httpStatus(result), result)
+	})
+}
+
+// httpStatus 返回执行结果对应的 HTTP 状态码
+func httpStatus(result ExecutionResult) int {
+	switch result.ErrorKind {
+	case ErrorKindInvalidRequest, ErrorKindLanguageUnsupported:
+		return http.StatusBadRequest
+	case ErrorKindCodeTooLarge:
+		return http.StatusRequestEntityTooLarge
+	case ErrorKindTimeout:
+		return http.StatusRequestTimeout
+	case ErrorKindBusy, ErrorKindRateLimited, ErrorKindQueueFull:
+		return http.StatusTooManyRequests
+	case ErrorKindRuntimeUnavailable, ErrorKindShuttingDown:
+		return http.StatusServiceUnavailable
+	case ErrorKindInternal:
+		return http.StatusInternalServerError
+	}
+	return http.StatusOK
+}
+
+// writeJSON 以 status 写出 v 的 JSON 编码
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.Encode(v)
+}
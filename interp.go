@@ -0,0 +1,26 @@
+package sandbox
+
+import (
+	"os"
+	"os/exec"
+)
+
+// lookupInterpreter 按顺序在 PATH 中查找 candidates，返回第一个能够通过 check 的解释器路径。
+// 都不可用时返回第一个候选名称，由后续的可用性检查报告运行时缺失
+func lookupInterpreter(candidates []string, check func(string) bool) string {
+	for _, name := range candidates {
+		path, err := exec.LookPath(name)
+		if err == nil && check(path) {
+			return path
+		}
+	}
+	return candidates[0]
+}
+
+// tempRoot 返回创建临时文件所在的目录
+func (e *CodeExecutor) tempRoot() string {
+	if e.tempDir != "" {
+		return e.tempDir
+	}
+	return os.TempDir()
+}
@@ -0,0 +1,9 @@
+//go:build !windows
+
+package sandbox
+
+// pythonCandidates 是未设置 Config.PythonPath 时依次尝试的解释器
+var pythonCandidates = []string{"python", "python3"}
+
+// nodeCandidates 是未设置 Config.NodePath 时依次尝试的解释器，部分发行版将 Node.js 安装为 nodejs
+var nodeCandidates = []string{"node", "nodejs"}
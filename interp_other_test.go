@@ -0,0 +1,28 @@
+//go:build !windows
+
+package sandbox
+
+import "testing"
+
+func TestInterpreterCandidates(t *testing.T) {
+	tests := []struct {
+		name       string
+		candidates []string
+		present    string
+	}{
+		{"python", pythonCandidates, "python"},
+		{"python3 only", pythonCandidates, "python3"},
+		{"node", nodeCandidates, "node"},
+		{"nodejs only", nodeCandidates, "nodejs"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			want := writeExecutable(t, dir, tt.present)
+			t.Setenv("PATH", dir)
+			if got := lookupInterpreter(tt.candidates, func(string) bool { return true }); got != want {
+				t.Errorf("lookupInterpreter = %q，期望 %q", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package sandbox
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// writeExecutable 在 dir 中创建名为 name 的可执行文件（Windows 上加 .exe 扩展名），返回其路径
+func writeExecutable(t *testing.T, dir, name string) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		name += ".exe"
+	}
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLookupInterpreter(t *testing.T) {
+	tests := []struct {
+		name     string
+		present  []string // PATH 中存在的程序
+		broken   []string // 存在但无法通过检查的程序
+		want     string   // 期望找到的程序，为空时期望返回第一个候选名称
+		wantPath bool
+	}{
+		{"first candidate", []string{"alpha", "beta"}, nil, "alpha", true},
+		{"first missing", []string{"beta"}, nil, "beta", true},
+		{"first fails check", []string{"alpha", "beta"}, []string{"alpha"}, "beta", true},
+		{"none usable", []string{"alpha"}, []string{"alpha"}, "alpha", false},
+		{"none present", nil, nil, "alpha", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			paths := map[string]string{}
+			for _, name := range tt.present {
+				paths[name] = writeExecutable(t, dir, name)
+			}
+			t.Setenv("PATH", dir)
+			check := func(path string) bool {
+				for _, name := range tt.broken {
+					if path == paths[name] {
+						return false
+					}
+				}
+				return true
+			}
+			want := tt.want
+			if tt.wantPath {
+				want = paths[tt.want]
+			}
+			if got := lookupInterpreter([]string{"alpha", "beta"}, check); got != want {
+				t.Errorf("lookupInterpreter = %q，期望 %q", got, want)
+			}
+		})
+	}
+}
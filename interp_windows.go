@@ -0,0 +1,10 @@
+//go:build windows
+
+package sandbox
+
+// pythonCandidates 是未设置 Config.PythonPath 时依次尝试的解释器。
+// 商店版的 python.exe 占位程序无法运行，会被可用性检查跳过，此时退回 py 启动器
+var pythonCandidates = []string{"python", "python3", "py"}
+
+// nodeCandidates 是未设置 Config.NodePath 时依次尝试的解释器
+var nodeCandidates = []string{"node"}
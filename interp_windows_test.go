@@ -0,0 +1,35 @@
+//go:build windows
+
+package sandbox
+
+import "testing"
+
+func TestInterpreterCandidates(t *testing.T) {
+	tests := []struct {
+		name       string
+		candidates []string
+		present    []string
+		broken     string // 存在但无法运行的程序，如商店版 python.exe 占位程序
+		want       string
+	}{
+		{"python.exe", pythonCandidates, []string{"python", "py"}, "", "python"},
+		{"store stub falls back to py", pythonCandidates, []string{"python", "py"}, "python", "py"},
+		{"py launcher only", pythonCandidates, []string{"py"}, "", "py"},
+		{"node.exe", nodeCandidates, []string{"node"}, "", "node"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			paths := map[string]string{}
+			for _, name := range tt.present {
+				paths[name] = writeExecutable(t, dir, name)
+			}
+			t.Setenv("PATH", dir)
+			t.Setenv("PATHEXT", ".EXE")
+			check := func(path string) bool { return tt.broken == "" || path != paths[tt.broken] }
+			if got := lookupInterpreter(tt.candidates, check); got != paths[tt.want] {
+				t.Errorf("lookupInterpreter = %q，期望 %q", got, paths[tt.want])
+			}
+		})
+	}
+}
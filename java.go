@@ -0,0 +1,124 @@
+package sandbox
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// javaPublicType 匹配 public 类型声明，其中位于顶层（见 javaBraceDepth）的才需要与源文件同名
+var javaPublicType = regexp.MustCompile(`(?m)^[ \t]*public\s+(?:(?:final|abstract|sealed|non-sealed|strictfp|static)\s+)*(?:class|interface|enum|record)\s+([A-Za-z_$][A-Za-z0-9_$]*)`)
+
+// javaPackage 匹配源文件开头的 package 声明
+var javaPackage = regexp.MustCompile(`(?m)^[ \t]*package\s+([A-Za-z_$][A-Za-z0-9_$.]*)\s*;`)
+
+// javaClassName 返回代码中顶层 public 类型的名称，嵌套的 public 类型（如 public static class Helper）不算，
+// 没有顶层 public 类型时使用 Main
+func javaClassName(code string) string {
+	for _, match := range javaPublicType.FindAllStringSubmatchIndex(code, -1) {
+		if javaBraceDepth(code[:match[0]]) == 0 {
+			return code[match[2]:match[3]]
+		}
+	}
+	return "Main"
+}
+
+// javaBraceDepth 返回 code 末尾所处的花括号嵌套深度，跳过注释、字符串（含文本块）与字符字面量中的括号
+func javaBraceDepth(code string) int {
+	depth := 0
+	for i := 0; i < len(code); i++ {
+		switch {
+		case strings.HasPrefix(code[i:], "//"):
+			end := strings.IndexByte(code[i:], '\n')
+			if end < 0 {
+				return depth
+			}
+			i += end
+		case strings.HasPrefix(code[i:], "/*"):
+			end := strings.Index(code[i+2:], "*/")
+			if end < 0 {
+				return depth
+			}
+			i += end + 3
+		case strings.HasPrefix(code[i:], `"""`):
+			end := strings.Index(code[i+3:], `"""`)
+			if end < 0 {
+				return depth
+			}
+			i += end + 5
+		case code[i] == '"' || code[i] == '\'':
+			quote := code[i]
+			for i++; i < len(code) && code[i] != quote && code[i] != '\n'; i++ {
+				if code[i] == '\\' {
+					i++
+				}
+			}
+		case code[i] == '{':
+			depth++
+		case code[i] == '}':
+			depth--
+		}
+	}
+	return depth
+}
+
+// javaMainClass 返回源文件 src 中入口类的全限定名：类名取自文件名，包名取自 package 声明。
+// 单文件代码直接使用 code，多文件项目的入口文件从磁盘读取
+func javaMainClass(src string, code string) string {
+	if code == "" {
+		if content, err := os.ReadFile(src); err == nil {
+			code = string(content)
+		}
+	}
+	class := strings.TrimSuffix(filepath.Base(src), ".java")
+	if match := javaPackage.FindStringSubmatch(code); match != nil {
+		class = match[1] + "." + class
+	}
+	return class
+}
+
+// javaOptions 返回运行代码片段时传给 java 的参数：串行 GC 与只用 C1 编译器可以缩短启动时间；
+// 配置了 MaxMemoryBytes 时限制堆大小并缩小代码缓存与类空间的预留，否则 JVM 在 RLIMIT_AS 下无法启动
+func (e *CodeExecutor) javaOptions() []string {
+	options := []string{"-XX:+UseSerialGC", "-XX:TieredStopAtLevel=1"}
+	if limit := e.limits.maxMemoryBytes; limit > 0 {
+		options = append(options,
+			"-Xmx"+strconv.FormatInt(limit/2>>20, 10)+"m",
+			"-XX:ReservedCodeCacheSize=32m",
+			"-XX:CompressedClassSpaceSize=64m",
+		)
+	}
+	return options
+}
+
+// runJavaCode 先用 javac 将代码编译到临时目录，再以该目录为类路径用 java 运行入口类。
+// 源文件以代码中顶层 public 类的名称命名，没有顶层 public 类时命名为 Main.java 并运行 Main；
+// 源文件与生成的 .class 文件随临时目录一起删除。编译错误与运行时异常分别以
+// ErrorKindCompileError 与非零退出码报告
+func (e *CodeExecutor) runJavaCode(ctx context.Context, code string, opts ExecOptions) ExecutionResult {
+	return e.runCompiled(ctx, code, opts, compiledLanguage{
+		language: "java",
+		name:     "Java",
+		source:   javaClassName(code) + ".java",
+		compile: func(ctx context.Context, src string, dir string) *exec.Cmd {
+			// 多文件项目中入口类引用的其他类按 -sourcepath 一并编译
+			return exec.CommandContext(ctx, "javac", "-encoding", "UTF-8", "-sourcepath", filepath.Dir(src), "-d", dir, src)
+		},
+		run: func(ctx context.Context, src string, dir string) *exec.Cmd {
+			args := append(e.javaOptions(), "-cp", dir, javaMainClass(src, code))
+			return exec.CommandContext(ctx, "java", args...)
+		},
+	})
+}
+
+// javaAvailable 判断 JDK 是否可用：编译需要 javac，运行需要 java
+func javaAvailable() (version string, ok bool) {
+	if _, err := exec.LookPath("java"); err != nil {
+		return "", false
+	}
+	return runtimeVersion("javac", "-version")
+}
@@ -0,0 +1,29 @@
+package sandbox
+
+import "testing"
+
+func TestJavaClassName(t *testing.T) {
+	tests := []struct {
+		name string
+		code string
+		want string
+	}{
+		{"top-level class", "public class Hello {\n  public static void main(String[] a) {}\n}", "Hello"},
+		{"no public type", "class Hello {\n  public static void main(String[] a) {}\n}", "Main"},
+		{"nested public class only", "class Main {\n  public static class Helper {}\n  public static void main(String[] a) {}\n}", "Main"},
+		{"nested before top-level", "class Util {\n    public static class Helper {}\n}\npublic final class App {\n}", "App"},
+		{"nested unindented", "class Main {\npublic static class Helper {}\n}", "Main"},
+		{"nested record", "class Main {\n  public record Point(int x, int y) {}\n}", "Main"},
+		{"nested interface", "class Main {\n  public interface Shape {}\n}", "Main"},
+		{"top-level record", "public record Point(int x, int y) {\n  public static void main(String[] a) {}\n}", "Point"},
+		{"top-level interface", "import java.util.*;\n\npublic interface Greeter {\n  public static class Impl {}\n}", "Greeter"},
+		{"braces in strings and comments", "class A {\n  String s = \"}\"; char c = '}'; // }\n  /* } */\n  String t = \"\"\"\n    }\n    \"\"\";\n  public static class Helper {}\n}", "Main"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := javaClassName(tt.code); got != tt.want {
+				t.Errorf("javaClassName = %q，期望 %q", got, tt.want)
+			}
+		})
+	}
+}
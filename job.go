@@ -0,0 +1,69 @@
+package sandbox
+
+import "context"
+
+// defaultMaxPendingJobs 是 Config.MaxPendingJobs 未设置时允许同时存在的异步任务数
+const defaultMaxPendingJobs = 100
+
+// Job 是通过 Submit 提交的异步执行任务
+type Job struct {
+	id     string
+	cancel context.CancelFunc
+	done   chan struct{}
+	result ExecutionResult
+}
+
+// ID 返回任务的执行ID，与结果中的 ID 及 CodeExecutor.Cancel 使用的ID一致
+func (j *Job) ID() string {
+	return j.id
+}
+
+// Done 返回一个在任务结束时关闭的通道
+func (j *Job) Done() <-chan struct{} {
+	return j.done
+}
+
+// Wait 阻塞直到任务结束并返回执行结果
+func (j *Job) Wait() ExecutionResult {
+	<-j.done
+	return j.result
+}
+
+// Cancel 取消任务，无论其仍在排队还是已经在运行
+func (j *Job) Cancel() {
+	j.cancel()
+}
+
+// Submit 异步提交代码执行任务并立即返回。任务仍受工作池并发数的约束，
+// 排队与运行中的任务总数达到 MaxPendingJobs 时返回 ErrQueueFull，执行器关闭后返回 ErrShuttingDown
+func (e *CodeExecutor) Submit(code string, language string) (*Job, error) {
+	if !e.admit() {
+		return nil, e.sandboxError(ErrorKindShuttingDown, MsgShuttingDown)
+	}
+	select {
+	case e.pending <- struct{}{}:
+	default:
+		e.inflight.Done()
+		e.metrics.rejected.Add(1)
+		e.emit(ExecEvent{Type: EventRejected, Language: language, ErrorKind: ErrorKindQueueFull})
+		return nil, e.sandboxError(ErrorKindQueueFull, MsgQueueFull)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	job := &Job{
+		id:     newExecutionID(),
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+
+	go func() {
+		defer e.inflight.Done()
+		defer func() { <-e.pending }()
+		defer cancel()
+
+		job.result = e.execute(ctx, code, language, ExecOptions{ID: job.id, admitted: true})
+		close(job.done)
+	}()
+
+	return job, nil
+}
@@ -0,0 +1,70 @@
+package sandbox
+
+import "time"
+
+// supportedLanguages 是内置的语言名称
+var supportedLanguages = []string{
+	"python3",
+	"nodejs",
+	"go",
+	"c",
+	"rust",
+	"java",
+	"ruby",
+	"bash",
+	"typescript",
+	"deno",
+	"sql",
+	"wasm",
+}
+
+// SupportedLanguages 返回执行器能够识别的全部语言，包括通过 RegisterRunner 注册的语言，
+// 与当前主机是否安装了对应解释器无关
+func (e *CodeExecutor) SupportedLanguages() []string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return append([]string(nil), e.languages...)
+}
+
+// AvailableLanguages 返回当前主机上解释器通过可用性检查的语言
+func (e *CodeExecutor) AvailableLanguages() []string {
+	var available []string
+	for _, language := range e.SupportedLanguages() {
+		if e.IsLanguageAvailable(language) {
+			available = append(available, language)
+		}
+	}
+	return available
+}
+
+// IsLanguageAvailable 判断 language 是否受支持且其解释器可用
+func (e *CodeExecutor) IsLanguageAvailable(language string) bool {
+	return e.unavailableError(language) == nil
+}
+
+// RefreshRuntimes 重新探测各内置语言的运行时并更新可用性与版本信息，
+// 使启动后安装或卸载的解释器生效。可与执行并发调用，进行中的执行不受影响
+func (e *CodeExecutor) RefreshRuntimes() {
+	runtimes := probeRuntimes(e.pythonPath, e.nodePath)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.runtimes = runtimes
+}
+
+// pollRuntimes 每隔 interval 调用一次 RefreshRuntimes，直到 closed 关闭
+func (e *CodeExecutor) pollRuntimes(interval time.Duration, closed <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			e.RefreshRuntimes()
+		case <-closed:
+			return
+		}
+	}
+}
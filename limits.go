@@ -0,0 +1,67 @@
+package sandbox
+
+import (
+	"os"
+	"strings"
+	"time"
+)
+
+// resourceLimits 是施加在子进程上的资源限制，零值或负数字段表示不限制
+type resourceLimits struct {
+	maxMemoryBytes int64
+	maxCPUSeconds  int64
+	maxProcesses   int64
+	maxOpenFiles   int64
+}
+
+// memoryErrorMarkers 是各解释器在内存分配失败时输出到 stderr 的典型信息
+var memoryErrorMarkers = []string{
+	"MemoryError",
+	"out of memory",
+	"Cannot allocate memory",
+	"allocation failed",
+	"Fatal process OOM",
+	"std::bad_alloc",
+}
+
+// annotateLimits 在受限执行失败时根据 stderr 和进程状态判断是否触发了资源限制，
+// 或是否被内核的 OOM killer 终止（见 oomLikely），并在 Error 前补充明确的说明
+func (e *CodeExecutor) annotateLimits(result ExecutionResult, state *os.ProcessState) ExecutionResult {
+	limits := e.limits
+	if result.Success || !limitsSupported {
+		return result
+	}
+	if limits.maxCPUSeconds > 0 && state != nil && cpuLimitExceeded(state, time.Duration(limits.maxCPUSeconds)*time.Second) {
+		result.Error = e.msg(MsgCPULimit, limits.maxCPUSeconds) + "\n" + result.Error
+		result.ErrorKind = ErrorKindCPULimit
+	} else if limits.maxMemoryBytes > 0 && memoryExhausted(result.Error) {
+		result.Error = e.msg(MsgMemoryLimit, limits.maxMemoryBytes) + "\n" + result.Error
+		result.ErrorKind = ErrorKindMemoryLimit
+	} else if state != nil && result.ErrorKind != ErrorKindOutputLimit && result.ErrorKind != ErrorKindOutputFlood &&
+		killedBySIGKILL(state) && oomLikely(limits, peakRSS(state)) {
+		result.Error = e.msg(MsgOutOfMemory, peakRSS(state)>>20) + "\n" + result.Error
+		result.ErrorKind = ErrorKindOutOfMemory
+	}
+	return result
+}
+
+// oomLikely 判断被 SIGKILL 终止、峰值内存为 peak 的进程是否可能是被 OOM killer 杀死的：
+// 配置了 MaxMemoryBytes，或者 peak 达到所在 cgroup（或整机）可用内存的 80%。
+// 其他 SIGKILL（如代码自己或外部发送的信号）保持原来的分类，只由 Signal 反映
+func oomLikely(limits resourceLimits, peak int64) bool {
+	if limits.maxMemoryBytes > 0 {
+		return true
+	}
+	ceiling := memoryCeiling()
+	return ceiling > 0 && peak >= ceiling/5*4
+}
+
+// memoryExhausted 根据 stderr 判断进程是否因内存分配失败而退出
+func memoryExhausted(stderr string) bool {
+	for _, marker := range memoryErrorMarkers {
+		if strings.Contains(stderr, marker) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,116 @@
+//go:build linux
+
+package sandbox
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// limitsSupported 表示当前平台能否对子进程施加 rlimit
+const limitsSupported = true
+
+// applyLimits 通过 util-linux 的 prlimit 包装 cmd，在 exec 解释器之前设置 rlimit，
+// 限制随后由解释器派生的所有子进程继承。没有任何限制时不修改 cmd
+func applyLimits(cmd *exec.Cmd, limits resourceLimits) error {
+	var args []string
+	if limits.maxMemoryBytes > 0 {
+		args = append(args, "--as="+strconv.FormatInt(limits.maxMemoryBytes, 10))
+	}
+	if limits.maxCPUSeconds > 0 {
+		// 软限制到期时内核发送 SIGXCPU，若进程捕获了该信号，1 秒后的硬限制以 SIGKILL 终止它
+		args = append(args, fmt.Sprintf("--cpu=%d:%d", limits.maxCPUSeconds, limits.maxCPUSeconds+1))
+	}
+	if limits.maxProcesses > 0 {
+		args = append(args, "--nproc="+strconv.FormatInt(limits.maxProcesses, 10))
+	}
+	if limits.maxOpenFiles > 0 {
+		args = append(args, "--nofile="+strconv.FormatInt(limits.maxOpenFiles, 10))
+	}
+	if len(args) == 0 {
+		return nil
+	}
+
+	prlimit, err := exec.LookPath("prlimit")
+	if err != nil {
+		return err
+	}
+	args = append([]string{prlimit}, args...)
+	args = append(args, "--", cmd.Path)
+	cmd.Args = append(args, cmd.Args[1:]...)
+	cmd.Path = prlimit
+	return nil
+}
+
+// memoryCeiling 返回服务进程（及其子进程）可用内存的上限：所在 cgroup 的内存限制与整机内存中较小的一个，
+// 都无法读取时返回 0
+func memoryCeiling() int64 {
+	ceiling := hostMemory()
+	if limit := cgroupMemoryLimit(); limit > 0 && (ceiling == 0 || limit < ceiling) {
+		ceiling = limit
+	}
+	return ceiling
+}
+
+// hostMemory 返回 /proc/meminfo 中的 MemTotal（字节）
+func hostMemory() int64 {
+	file, err := os.Open("/proc/meminfo")
+	if err != nil {
+		return 0
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		// 格式为 "MemTotal:       16314588 kB"
+		if value, ok := strings.CutPrefix(scanner.Text(), "MemTotal:"); ok {
+			kb, err := strconv.ParseInt(strings.TrimSuffix(strings.TrimSpace(value), " kB"), 10, 64)
+			if err != nil {
+				return 0
+			}
+			return kb << 10
+		}
+	}
+	return 0
+}
+
+// cgroupMemoryLimit 返回服务进程所在 cgroup 的内存限制（字节），依次尝试 cgroup v2 的 memory.max
+// 与 v1 的 memory.limit_in_bytes，没有限制或无法读取时返回 0
+func cgroupMemoryLimit() int64 {
+	// /proc/self/cgroup 每行为 "层级ID:控制器:路径"，cgroup v2 的一行为 "0::路径"。
+	// 容器中 cgroup 文件系统通常以自身的 cgroup 为根挂载，因此最后回退到挂载点根目录
+	var files []string
+	if data, err := os.ReadFile("/proc/self/cgroup"); err == nil {
+		for _, line := range strings.Split(string(data), "\n") {
+			fields := strings.SplitN(line, ":", 3)
+			if len(fields) != 3 {
+				continue
+			}
+			if fields[1] == "" {
+				files = append(files, filepath.Join("/sys/fs/cgroup", fields[2], "memory.max"))
+			} else if slices.Contains(strings.Split(fields[1], ","), "memory") {
+				files = append(files, filepath.Join("/sys/fs/cgroup/memory", fields[2], "memory.limit_in_bytes"))
+			}
+		}
+	}
+	files = append(files, "/sys/fs/cgroup/memory.max", "/sys/fs/cgroup/memory/memory.limit_in_bytes")
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			continue
+		}
+		// v2 以 "max" 表示不限制，v1 以接近 int64 上限的值表示不限制
+		limit, err := strconv.ParseInt(string(bytes.TrimSpace(data)), 10, 64)
+		if err != nil || limit <= 0 || limit >= 1<<62 {
+			return 0
+		}
+		return limit
+	}
+	return 0
+}
@@ -0,0 +1,155 @@
+package sandbox
+
+import (
+	"os"
+	"os/exec"
+	"os/user"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// forkLoop 不断 fork 直到失败或达到 100 个子进程，打印成功 fork 的次数与是否遇到失败
+const forkLoop = `import os, time
+pids, failed = [], False
+for _ in range(100):
+    try:
+        pid = os.fork()
+    except OSError:
+        failed = True
+        break
+    if pid == 0:
+        time.sleep(1)
+        os._exit(0)
+    pids.append(pid)
+for pid in pids:
+    os.waitpid(pid, 0)
+print(len(pids), failed)
+`
+
+func TestMaxProcessesBoundsForkLoop(t *testing.T) {
+	if _, err := exec.LookPath("prlimit"); err != nil {
+		t.Skip("需要 util-linux 的 prlimit")
+	}
+	// RLIMIT_NPROC 对 root 无效，以 root 运行测试时改用 nobody 执行代码，解释器须对 nobody 可执行
+	var config Config
+	if os.Geteuid() == 0 {
+		if _, err := user.Lookup("nobody"); err != nil {
+			t.Skip("以 root 运行时需要 nobody 用户")
+		}
+		if _, err := os.Stat(systemPython); err != nil {
+			t.Skipf("以 root 运行时需要 %s", systemPython)
+		}
+		config.RunAsUser, config.PythonPath = "nobody", systemPython
+	}
+
+	tests := []struct {
+		name         string
+		maxProcesses int64
+		wantFailed   bool
+	}{
+		{"limit 4", 4, true},
+		{"limit 16", 16, true},
+		{"unlimited", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := config
+			config.MaxProcesses = tt.maxProcesses
+			e := newTestExecutor(t, "python3", config)
+			result := e.Execute(forkLoop, "python3")
+			if !result.Success {
+				t.Fatalf("执行失败: %s", result.Error)
+			}
+			fields := strings.Fields(result.Output)
+			if len(fields) != 2 {
+				t.Fatalf("输出格式不符: %q", result.Output)
+			}
+			forked, _ := strconv.Atoi(fields[0])
+			if failed := fields[1] == "True"; failed != tt.wantFailed {
+				t.Errorf("fork 失败 = %v，期望 %v（成功 %d 次）", failed, tt.wantFailed, forked)
+			}
+			if tt.maxProcesses > 0 && int64(forked) >= tt.maxProcesses {
+				t.Errorf("成功 fork %d 次，超过上限 %d", forked, tt.maxProcesses)
+			}
+		})
+	}
+}
+
+func TestMaxCPUSecondsStopsBusyLoop(t *testing.T) {
+	if _, err := exec.LookPath("prlimit"); err != nil {
+		t.Skip("需要 util-linux 的 prlimit")
+	}
+	tests := []struct {
+		name          string
+		maxCPUSeconds int64
+		timeout       time.Duration
+		wantKind      ErrorKind
+	}{
+		{"cpu limit", 1, 20 * time.Second, ErrorKindCPULimit},
+		{"wall clock timeout", 0, time.Second, ErrorKindTimeout},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newTestExecutor(t, "python3", Config{MaxCPUSeconds: tt.maxCPUSeconds})
+			result := e.ExecuteWithOptions("while True:\n    pass\n", "python3", ExecOptions{Timeout: tt.timeout})
+			if result.ErrorKind != tt.wantKind {
+				t.Fatalf("ErrorKind = %q，期望 %q（%s）", result.ErrorKind, tt.wantKind, result.Error)
+			}
+		})
+	}
+}
+
+func TestSIGKILLClassification(t *testing.T) {
+	const selfKill = "import os, signal\nos.kill(os.getpid(), signal.SIGKILL)"
+	tests := []struct {
+		name           string
+		maxMemoryBytes int64
+		wantKind       ErrorKind
+	}{
+		{"no memory limit", 0, ErrorKindRuntimeError},
+		{"memory limit", 1 << 30, ErrorKindOutOfMemory},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := exec.LookPath("prlimit"); err != nil && tt.maxMemoryBytes > 0 {
+				t.Skip("需要 util-linux 的 prlimit")
+			}
+			e := newTestExecutor(t, "python3", Config{MaxMemoryBytes: tt.maxMemoryBytes})
+			result := e.Execute(selfKill, "python3")
+			if result.ErrorKind != tt.wantKind {
+				t.Errorf("ErrorKind = %q，期望 %q（%s）", result.ErrorKind, tt.wantKind, result.Error)
+			}
+			if result.Signal != "SIGKILL" {
+				t.Errorf("Signal = %q，期望 SIGKILL", result.Signal)
+			}
+		})
+	}
+}
+
+func TestOOMLikely(t *testing.T) {
+	ceiling := memoryCeiling()
+	if ceiling == 0 {
+		t.Skip("无法读取可用内存")
+	}
+	tests := []struct {
+		name   string
+		limits resourceLimits
+		peak   int64
+		want   bool
+	}{
+		{"memory limit configured", resourceLimits{maxMemoryBytes: 1 << 30}, 0, true},
+		{"small peak", resourceLimits{}, 10 << 20, false},
+		{"no rusage", resourceLimits{}, 0, false},
+		{"peak near ceiling", resourceLimits{}, ceiling / 10 * 9, true},
+		{"peak at ceiling", resourceLimits{}, ceiling, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := oomLikely(tt.limits, tt.peak); got != tt.want {
+				t.Errorf("oomLikely(%+v, %d) = %v，期望 %v", tt.limits, tt.peak, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,18 @@
+//go:build !linux
+
+package sandbox
+
+import "os/exec"
+
+// limitsSupported 表示当前平台能否对子进程施加 rlimit
+const limitsSupported = false
+
+// memoryCeiling 在其他平台上返回 0，表示无法得知可用内存
+func memoryCeiling() int64 {
+	return 0
+}
+
+// applyLimits 在不支持 rlimit 的平台上为空操作，资源限制不会生效
+func applyLimits(cmd *exec.Cmd, limits resourceLimits) error {
+	return nil
+}
@@ -1,5 +0,0 @@
-package main
-
-func main(){
-	fmt.Println("it's a code executor sandbox")
-}
\ No newline at end of file
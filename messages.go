@@ -0,0 +1,269 @@
+package sandbox
+
+import "fmt"
+
+// MessageID 标识一条面向用户的提示信息，与 ErrorKind 不同，它只决定展示的文字
+type MessageID string
+
+const (
+	MsgTimeout             MessageID = "timeout"                // 参数: 超时秒数 (float64)
+	MsgCanceled            MessageID = "canceled"               //
+	MsgLanguageUnsupported MessageID = "language_unsupported"   // 参数: 语言名
+	MsgRuntimeUnavailable  MessageID = "runtime_unavailable"    // 参数: 运行时名称
+	MsgCompileFailed       MessageID = "compile_failed"         // 参数: 语言名称
+	MsgOutputLimit         MessageID = "output_limit"           // 参数: 字节数
+	MsgCPULimit            MessageID = "cpu_limit"              // 参数: 秒数
+	MsgMemoryLimit         MessageID = "memory_limit"           // 参数: 字节数
+	MsgQueueFull           MessageID = "queue_full"             //
+	MsgDuplicateID         MessageID = "duplicate_id"           // 参数: 执行ID
+	MsgEntryNotFound       MessageID = "entry_not_found"        // 参数: 文件名
+	MsgInvalidFileName     MessageID = "invalid_file_name"      // 参数: 文件名
+	MsgWorkDirBlank        MessageID = "workdir_blank"          //
+	MsgWorkDirNotDir       MessageID = "workdir_not_dir"        // 参数: 路径
+	MsgWorkDirUnusable     MessageID = "workdir_unusable"       // 参数: 错误
+	MsgCreateTempFile      MessageID = "create_temp_file"       // 参数: 错误
+	MsgCreateTempDir       MessageID = "create_temp_dir"        // 参数: 错误
+	MsgWriteCode           MessageID = "write_code"             // 参数: 错误
+	MsgWriteFile           MessageID = "write_file"             // 参数: 文件名, 错误
+	MsgResolveEntry        MessageID = "resolve_entry"          // 参数: 错误
+	MsgReadWorkDir         MessageID = "read_workdir"           // 参数: 错误
+	MsgCollectFiles        MessageID = "collect_files"          // 参数: 错误
+	MsgStartProcess        MessageID = "start_process"          // 参数: 错误
+	MsgLimitsUnavailable   MessageID = "limits_unavailable"     // 参数: 错误
+	MsgNetworkUnsupported  MessageID = "network_unsupported"    //
+	MsgBusy                MessageID = "busy"                   //
+	MsgShuttingDown        MessageID = "shutting_down"          //
+	MsgRunAsUserInvalid    MessageID = "run_as_user_invalid"    // 参数: 错误
+	MsgRunAsRoot           MessageID = "run_as_root"            //
+	MsgDropPrivileges      MessageID = "drop_privileges"        // 参数: 错误
+	MsgImportDenied        MessageID = "import_denied"          // 参数: 模块列表
+	MsgPolicyCheck         MessageID = "policy_check"           // 参数: 错误
+	MsgCodeTooLarge        MessageID = "code_too_large"         // 参数: 字节数
+	MsgRateLimited         MessageID = "rate_limited"           // 参数: 客户端ID
+	MsgHealthCheckFailed   MessageID = "health_check_failed"    // 参数: 各运行时的失败原因
+	MsgUnexpectedOutput    MessageID = "unexpected_output"      // 参数: 实际输出
+	MsgNoRuntimeAvailable  MessageID = "no_runtime_available"   //
+	MsgRunnerPanic         MessageID = "runner_panic"           // 参数: 语言, panic 的值
+	MsgOutOfMemory         MessageID = "out_of_memory"          // 参数: 峰值内存 MiB
+	MsgSessionUnsupported  MessageID = "session_unsupported"    // 参数: 语言
+	MsgSessionClosed       MessageID = "session_closed"         //
+	MsgPackagesUnsupported MessageID = "packages_unsupported"   // 参数: 语言
+	MsgInvalidPackage      MessageID = "invalid_package"        // 参数: 依赖名称
+	MsgInstallFailed       MessageID = "install_failed"         //
+	MsgInstallTimeout      MessageID = "install_timeout"        // 参数: 秒数
+	MsgInvalidWasm         MessageID = "invalid_wasm"           //
+	MsgCircuitOpen         MessageID = "circuit_open"           // 参数: 语言, 连续失败次数
+	MsgOutputFlood         MessageID = "output_flood"           // 参数: 每秒字节数, 统计窗口秒数
+	MsgMethodNotAllowed    MessageID = "method_not_allowed"     // 参数: 请求方法
+	MsgInvalidRequestBody  MessageID = "invalid_request_body"   // 参数: 错误
+	MsgInvalidTimeout      MessageID = "invalid_timeout"        // 参数: 超时秒数
+	MsgReadCode            MessageID = "read_code"              // 参数: 错误
+	MsgCompileTimeout      MessageID = "compile_timeout"        // 参数: 超时秒数
+	MsgPythonLibInvalid    MessageID = "python_lib_invalid"     // 参数: 错误
+	MsgToolDescription     MessageID = "tool_description"       //
+	MsgToolBadArguments    MessageID = "tool_bad_arguments"     // 参数: 错误
+	MsgToolNegativeTimeout MessageID = "tool_negative_timeout"  //
+	MsgToolParamCode       MessageID = "tool_param_code"        //
+	MsgToolParamLanguage   MessageID = "tool_param_language"    //
+	MsgToolParamStdin      MessageID = "tool_param_stdin"       //
+	MsgToolParamTimeout    MessageID = "tool_param_timeout"     //
+	MsgToolResultID        MessageID = "tool_result_id"         //
+	MsgToolResultOutput    MessageID = "tool_result_output"     //
+	MsgToolResultError     MessageID = "tool_result_error"      //
+	MsgToolResultStderr    MessageID = "tool_result_stderr"     //
+	MsgToolResultErrorKind MessageID = "tool_result_error_kind" //
+	MsgToolResultSignal    MessageID = "tool_result_signal"     //
+	MsgToolResultTruncated MessageID = "tool_result_truncated"  //
+	MsgPackagesDisabled    MessageID = "packages_disabled"      //
+)
+
+// Catalog 将 MessageID 映射为 fmt 格式的消息模板
+type Catalog map[MessageID]string
+
+// 内置的消息目录，Config.Locale 为空或未知时使用中文
+var catalogs = map[string]Catalog{
+	"zh": {
+		MsgTimeout:             "代码执行超时 (>%g秒)",
+		MsgCanceled:            "代码执行已取消",
+		MsgLanguageUnsupported: "不支持的语言: %s",
+		MsgRuntimeUnavailable:  "%s未安装或不可用",
+		MsgCompileFailed:       "%s编译失败",
+		MsgOutputLimit:         "输出超出限制 (%d字节)，进程已终止",
+		MsgCPULimit:            "超出CPU时间限制 (%d秒)",
+		MsgMemoryLimit:         "超出内存限制 (%d字节)",
+		MsgQueueFull:           "任务队列已满",
+		MsgDuplicateID:         "执行ID已存在: %s",
+		MsgEntryNotFound:       "入口文件不存在: %s",
+		MsgInvalidFileName:     "非法的文件名: %q",
+		MsgWorkDirBlank:        "工作目录不能为空白",
+		MsgWorkDirNotDir:       "工作目录不是目录: %s",
+		MsgWorkDirUnusable:     "无法使用工作目录: %v",
+		MsgCreateTempFile:      "创建临时文件失败: %v",
+		MsgCreateTempDir:       "创建临时目录失败: %v",
+		MsgWriteCode:           "写入代码失败: %v",
+		MsgWriteFile:           "写入文件 %s 失败: %v",
+		MsgResolveEntry:        "解析入口文件失败: %v",
+		MsgReadWorkDir:         "读取工作目录失败: %v",
+		MsgCollectFiles:        "回收输出文件失败: %v",
+		MsgStartProcess:        "启动进程失败: %v",
+		MsgLimitsUnavailable:   "无法施加资源限制，未找到prlimit: %v",
+		MsgNetworkUnsupported:  "当前平台不支持网络隔离",
+		MsgBusy:                "没有空闲的执行槽位，请稍后重试",
+		MsgShuttingDown:        "执行器正在关闭，不再接受新的执行",
+		MsgRunAsUserInvalid:    "无效的运行用户: %v",
+		MsgRunAsRoot:           "已启用 RequireNonRoot，拒绝以root身份执行代码",
+		MsgDropPrivileges:      "无法切换运行用户: %v",
+		MsgImportDenied:        "禁止导入的模块: %s",
+		MsgPolicyCheck:         "导入检查失败: %v",
+		MsgCodeTooLarge:        "代码大小超出限制 (%d字节)",
+		MsgRateLimited:         "客户端 %q 的执行频率超出限制",
+		MsgHealthCheckFailed:   "运行时健康检查失败: %s",
+		MsgUnexpectedOutput:    "输出不符合预期: %q",
+		MsgNoRuntimeAvailable:  "没有可用的语言运行时",
+		MsgRunnerPanic:         "%s 的 Runner 发生 panic: %v",
+		MsgOutOfMemory:         "进程被 SIGKILL 终止，很可能是内存不足被系统的 OOM killer 杀死（峰值内存约 %d MiB）",
+		MsgSessionUnsupported:  "%s 不支持交互式会话，只支持 python3 与 nodejs",
+		MsgSessionClosed:       "会话已结束",
+		MsgPackagesUnsupported: "%s 不支持安装依赖（Packages）",
+		MsgInvalidPackage:      "非法的依赖: %q，只接受包索引中的名称与版本约束",
+		MsgInstallFailed:       "安装依赖失败",
+		MsgInstallTimeout:      "安装依赖超时 (>%g秒)",
+		MsgInvalidWasm:         "代码不是 WebAssembly 模块，应为原始的 .wasm 字节或其 base64 编码",
+		MsgCircuitOpen:         "%s 运行时已连续 %d 次启动失败，暂时停止执行，稍后会自动重试",
+		MsgOutputFlood:         "输出速率超出限制 (>%d字节/秒，统计窗口%g秒)，进程已被终止",
+		MsgMethodNotAllowed:    "不支持的请求方法 %s，请使用 POST",
+		MsgInvalidRequestBody:  "请求体不是合法的 JSON: %v",
+		MsgInvalidTimeout:      "超时时间不能为负数: %g",
+		MsgReadCode:            "读取代码失败: %v",
+		MsgCompileTimeout:      "编译超时 (>%g秒)",
+		MsgPythonLibInvalid:    "无效的 Python 共享库目录: %v",
+		MsgToolDescription:     "在隔离的沙箱中执行一段代码，返回标准输出、标准错误、退出码与耗时。每次执行都在新的进程中进行，不保留状态",
+		MsgToolBadArguments:    "参数不是合法的 JSON 对象: %v",
+		MsgToolNegativeTimeout: "timeout 不能为负数",
+		MsgToolParamCode:       "要执行的源代码",
+		MsgToolParamLanguage:   "代码的语言",
+		MsgToolParamStdin:      "传给程序的标准输入",
+		MsgToolParamTimeout:    "超时秒数，省略时使用服务端的默认超时",
+		MsgToolResultID:        "执行ID",
+		MsgToolResultOutput:    "标准输出",
+		MsgToolResultError:     "错误描述，通常包含标准错误",
+		MsgToolResultStderr:    "标准错误",
+		MsgToolResultErrorKind: "失败原因的分类，成功时省略",
+		MsgToolResultSignal:    "终止进程的信号",
+		MsgToolResultTruncated: "输出超出上限被截断",
+		MsgPackagesDisabled:    "未开启依赖安装（Config.AllowPackages）",
+	},
+	"en": {
+		MsgTimeout:             "execution timed out (>%gs)",
+		MsgCanceled:            "execution canceled",
+		MsgLanguageUnsupported: "unsupported language: %s",
+		MsgRuntimeUnavailable:  "%s is not installed or unavailable",
+		MsgCompileFailed:       "%s compilation failed",
+		MsgOutputLimit:         "output exceeded the limit (%d bytes), process terminated",
+		MsgCPULimit:            "CPU time limit exceeded (%ds)",
+		MsgMemoryLimit:         "memory limit exceeded (%d bytes)",
+		MsgQueueFull:           "job queue is full",
+		MsgDuplicateID:         "execution ID already in use: %s",
+		MsgEntryNotFound:       "entry file not found: %s",
+		MsgInvalidFileName:     "invalid file name: %q",
+		MsgWorkDirBlank:        "working directory must not be blank",
+		MsgWorkDirNotDir:       "working directory is not a directory: %s",
+		MsgWorkDirUnusable:     "cannot use working directory: %v",
+		MsgCreateTempFile:      "failed to create temp file: %v",
+		MsgCreateTempDir:       "failed to create temp directory: %v",
+		MsgWriteCode:           "failed to write code: %v",
+		MsgWriteFile:           "failed to write file %s: %v",
+		MsgResolveEntry:        "failed to resolve entry file: %v",
+		MsgReadWorkDir:         "failed to read working directory: %v",
+		MsgCollectFiles:        "failed to collect output files: %v",
+		MsgStartProcess:        "failed to start process: %v",
+		MsgLimitsUnavailable:   "cannot apply resource limits, prlimit not found: %v",
+		MsgNetworkUnsupported:  "network isolation is not supported on this platform",
+		MsgBusy:                "no worker is available, try again later",
+		MsgShuttingDown:        "executor is shutting down and no longer accepts new work",
+		MsgRunAsUserInvalid:    "invalid run-as user: %v",
+		MsgRunAsRoot:           "RequireNonRoot is set, refusing to run code as root",
+		MsgDropPrivileges:      "failed to switch to the run-as user: %v",
+		MsgImportDenied:        "disallowed imports: %s",
+		MsgPolicyCheck:         "import check failed: %v",
+		MsgCodeTooLarge:        "code exceeds the size limit (%d bytes)",
+		MsgRateLimited:         "client %q exceeded the execution rate limit",
+		MsgHealthCheckFailed:   "runtime health check failed: %s",
+		MsgUnexpectedOutput:    "unexpected output: %q",
+		MsgNoRuntimeAvailable:  "no language runtime is available",
+		MsgRunnerPanic:         "runner for %s panicked: %v",
+		MsgOutOfMemory:         "process was killed by SIGKILL, most likely by the out-of-memory killer (peak memory about %d MiB)",
+		MsgSessionUnsupported:  "%s does not support interactive sessions, only python3 and nodejs do",
+		MsgSessionClosed:       "session has ended",
+		MsgPackagesUnsupported: "%s does not support installing packages",
+		MsgInvalidPackage:      "invalid package %q: only registry names and version constraints are accepted",
+		MsgInstallFailed:       "failed to install packages",
+		MsgInstallTimeout:      "package installation timed out (>%gs)",
+		MsgInvalidWasm:         "code is not a WebAssembly module; expected raw .wasm bytes or base64",
+		MsgCircuitOpen:         "%s runtime failed to start %d times in a row; executions are paused and will be retried shortly",
+		MsgOutputFlood:         "output rate exceeded the limit (>%d bytes/s over %gs); the process was killed",
+		MsgMethodNotAllowed:    "method %s is not allowed; use POST",
+		MsgInvalidRequestBody:  "request body is not valid JSON: %v",
+		MsgInvalidTimeout:      "timeout must not be negative: %g",
+		MsgReadCode:            "failed to read code: %v",
+		MsgCompileTimeout:      "compilation timed out (>%gs)",
+		MsgPythonLibInvalid:    "invalid Python library directory: %v",
+		MsgToolDescription:     "Executes a snippet of code in an isolated sandbox and returns its standard output, standard error, exit code and duration. Every execution runs in a fresh process and keeps no state",
+		MsgToolBadArguments:    "arguments are not a valid JSON object: %v",
+		MsgToolNegativeTimeout: "timeout must not be negative",
+		MsgToolParamCode:       "source code to execute",
+		MsgToolParamLanguage:   "language of the code",
+		MsgToolParamStdin:      "standard input passed to the program",
+		MsgToolParamTimeout:    "timeout in seconds; the server default is used when omitted",
+		MsgToolResultID:        "execution ID",
+		MsgToolResultOutput:    "standard output",
+		MsgToolResultError:     "error description, usually including standard error",
+		MsgToolResultStderr:    "standard error",
+		MsgToolResultErrorKind: "category of the failure, omitted on success",
+		MsgToolResultSignal:    "signal that terminated the process",
+		MsgToolResultTruncated: "output exceeded the limit and was truncated",
+		MsgPackagesDisabled:    "package installation is disabled (Config.AllowPackages)",
+	},
+}
+
+// newCatalog 以 locale 对应的内置目录为基础，叠加 overrides 中的自定义模板
+func newCatalog(locale string, overrides Catalog) Catalog {
+	base, ok := catalogs[locale]
+	if !ok {
+		base = catalogs["zh"]
+	}
+	catalog := make(Catalog, len(base)+len(overrides))
+	for id, template := range base {
+		catalog[id] = template
+	}
+	for id, template := range overrides {
+		catalog[id] = template
+	}
+	return catalog
+}
+
+// msg 按执行器的消息目录格式化一条消息
+func (e *CodeExecutor) msg(id MessageID, args ...any) string {
+	template, ok := e.messages[id]
+	if !ok {
+		template = catalogs["zh"][id]
+	}
+	return fmt.Sprintf(template, args...)
+}
+
+// Message 按执行器的 Locale 与 Messages 格式化消息 id，供在执行器之上提供接口的包（如 sandboxmcp）使用
+func (e *CodeExecutor) Message(id MessageID, args ...any) string {
+	return e.msg(id, args...)
+}
+
+// sandboxError 构造一个使用本地化消息的 *SandboxError
+func (e *CodeExecutor) sandboxError(kind ErrorKind, id MessageID, args ...any) *SandboxError {
+	return &SandboxError{Kind: kind, Message: e.msg(id, args...), transient: anyTransient(args)}
+}
+
+// fail 构造一个使用本地化消息的失败结果
+func (e *CodeExecutor) fail(kind ErrorKind, id MessageID, args ...any) ExecutionResult {
+	result := errorResult(kind, e.msg(id, args...))
+	result.transient = anyTransient(args)
+	return result
+}
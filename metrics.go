@@ -0,0 +1,139 @@
+package sandbox
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// Metrics 是执行器自创建以来的累计计数快照
+type Metrics struct {
+	Executions uint64 `json:"executions"`
+	Successes  uint64 `json:"successes"`
+	Failures   uint64 `json:"failures"` // 所有未成功的执行，包括超时
+	Timeouts   uint64 `json:"timeouts"`
+	Rejected   uint64 `json:"rejected"` // 因队列已满被 Submit 拒绝的任务与因工作池已满以 ErrorKindBusy 失败的执行
+
+	// ByLanguage 按语言统计，只包含已注册的语言
+	ByLanguage map[string]LanguageMetrics `json:"by_language"`
+}
+
+// LanguageMetrics 是单个语言的累计计数
+type LanguageMetrics struct {
+	Executions uint64 `json:"executions"`
+	Successes  uint64 `json:"successes"`
+	Failures   uint64 `json:"failures"`
+	Timeouts   uint64 `json:"timeouts"`
+}
+
+// Stats 是执行器当前的负载状态
+type Stats struct {
+	InFlight int `json:"in_flight"` // 正在运行的执行数
+	Queued   int `json:"queued"`    // 等待工作池令牌的执行数
+}
+
+// durationBuckets 是执行耗时直方图各桶的上界（秒）
+var durationBuckets = [...]float64{0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}
+
+// counters 是一组原子计数器，可被并发的执行同时更新
+type counters struct {
+	executions atomic.Uint64
+	successes  atomic.Uint64
+	failures   atomic.Uint64
+	timeouts   atomic.Uint64
+
+	// durations[i] 是耗时不超过 durationBuckets[i] 的执行数（不累积），超出最后一个上界的只计入 executions
+	durations  [len(durationBuckets)]atomic.Uint64
+	durationMs atomic.Uint64 // 所有执行的耗时之和
+}
+
+func (c *counters) record(result ExecutionResult) {
+	c.executions.Add(1)
+	c.durationMs.Add(uint64(max(result.DurationMs, 0)))
+	seconds := float64(result.DurationMs) / 1000
+	for i, bound := range durationBuckets {
+		if seconds <= bound {
+			c.durations[i].Add(1)
+			break
+		}
+	}
+	if result.Success {
+		c.successes.Add(1)
+		return
+	}
+	c.failures.Add(1)
+	if result.ErrorKind == ErrorKindTimeout {
+		c.timeouts.Add(1)
+	}
+}
+
+func (c *counters) snapshot() LanguageMetrics {
+	return LanguageMetrics{
+		Executions: c.executions.Load(),
+		Successes:  c.successes.Load(),
+		Failures:   c.failures.Load(),
+		Timeouts:   c.timeouts.Load(),
+	}
+}
+
+// metrics 汇总执行器的计数。按语言的计数器在注册语言时分配，mu 只保护 map 本身
+type metrics struct {
+	total      counters
+	rejected   atomic.Uint64
+	mu         sync.RWMutex
+	byLanguage map[string]*counters
+}
+
+func newMetrics() *metrics {
+	m := &metrics{byLanguage: make(map[string]*counters, len(supportedLanguages))}
+	for _, language := range supportedLanguages {
+		m.byLanguage[language] = &counters{}
+	}
+	return m
+}
+
+// addLanguage 为新注册的语言分配计数器，已存在时保留原有计数
+func (m *metrics) addLanguage(language string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.byLanguage[language]; !ok {
+		m.byLanguage[language] = &counters{}
+	}
+}
+
+func (m *metrics) record(language string, result ExecutionResult) {
+	m.total.record(result)
+	m.mu.RLock()
+	c, ok := m.byLanguage[language]
+	m.mu.RUnlock()
+	if ok {
+		c.record(result)
+	}
+}
+
+// Metrics 返回执行计数的快照，可安全地与执行并发调用
+func (e *CodeExecutor) Metrics() Metrics {
+	total := e.metrics.total.snapshot()
+	snapshot := Metrics{
+		Executions: total.Executions,
+		Successes:  total.Successes,
+		Failures:   total.Failures,
+		Timeouts:   total.Timeouts,
+		Rejected:   e.metrics.rejected.Load(),
+	}
+	e.metrics.mu.RLock()
+	snapshot.ByLanguage = make(map[string]LanguageMetrics, len(e.metrics.byLanguage))
+	for language, c := range e.metrics.byLanguage {
+		snapshot.ByLanguage[language] = c.snapshot()
+	}
+	e.metrics.mu.RUnlock()
+	return snapshot
+}
+
+// Stats 返回当前正在运行和排队等待的执行数
+func (e *CodeExecutor) Stats() Stats {
+	return Stats{
+		InFlight: e.ActiveCount(),
+		Queued:   e.QueuedCount(),
+	}
+}
@@ -0,0 +1,27 @@
+//go:build linux
+
+package sandbox
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// isolateNetwork 让 cmd 运行在新的网络命名空间中，其中只有一个未启用的回环接口，
+// 任何对外的 socket 连接都会失败。非 root 用户运行时同时创建用户命名空间，
+// 将当前用户映射为命名空间内的同一ID，以便无特权地创建网络命名空间
+func isolateNetwork(cmd *exec.Cmd) error {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Cloneflags |= syscall.CLONE_NEWNET
+
+	if uid := os.Geteuid(); uid != 0 {
+		gid := os.Getegid()
+		cmd.SysProcAttr.Cloneflags |= syscall.CLONE_NEWUSER
+		cmd.SysProcAttr.UidMappings = []syscall.SysProcIDMap{{ContainerID: uid, HostID: uid, Size: 1}}
+		cmd.SysProcAttr.GidMappings = []syscall.SysProcIDMap{{ContainerID: gid, HostID: gid, Size: 1}}
+	}
+	return nil
+}
@@ -0,0 +1,13 @@
+//go:build !linux
+
+package sandbox
+
+import (
+	"errors"
+	"os/exec"
+)
+
+// isolateNetwork 在不支持网络命名空间的平台上返回错误，避免在未隔离网络的情况下运行代码
+func isolateNetwork(cmd *exec.Cmd) error {
+	return errors.ErrUnsupported
+}
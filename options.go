@@ -0,0 +1,238 @@
+package sandbox
+
+import (
+	"os"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// defaultTimeout 是未指定超时时间时单次执行的超时
+const defaultTimeout = 30 * time.Second
+
+// defaultKillGrace 是未指定 KillGracePeriod 时 SIGTERM 与 SIGKILL 之间的宽限期
+const defaultKillGrace = 2 * time.Second
+
+// Option 修改创建执行器时使用的 Config，供 New 使用。
+// 除下列 With 函数外，也可以直接编写 func(*Config) 设置其他字段
+type Option func(*Config)
+
+// New 以默认配置叠加 opts 创建执行器：超时 30 秒，最大并发数为 CPU 核数。
+// 选项按顺序应用，后面的选项覆盖前面的
+func New(opts ...Option) *CodeExecutor {
+	var config Config
+	for _, opt := range opts {
+		opt(&config)
+	}
+	return NewCodeExecutorWithConfig(config)
+}
+
+// WithTimeout 设置单次执行的默认超时时间
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *Config) { c.Timeout = timeout }
+}
+
+// WithLanguageTimeout 设置 language 的默认超时，覆盖 WithTimeout 设置的全局默认值，见 Config.LanguageTimeouts
+func WithLanguageTimeout(language string, timeout time.Duration) Option {
+	return func(c *Config) {
+		timeouts := make(map[string]time.Duration, len(c.LanguageTimeouts)+1)
+		for name, value := range c.LanguageTimeouts {
+			timeouts[name] = value
+		}
+		timeouts[language] = timeout
+		c.LanguageTimeouts = timeouts
+	}
+}
+
+// WithCompileTimeout 为 Go、C、Rust、Java 的编译阶段单独设置超时，见 Config.CompileTimeout
+func WithCompileTimeout(timeout time.Duration) Option {
+	return func(c *Config) { c.CompileTimeout = timeout }
+}
+
+// WithKillGracePeriod 设置超时或取消时 SIGTERM 与 SIGKILL 之间的宽限期，见 Config.KillGracePeriod
+func WithKillGracePeriod(grace time.Duration) Option {
+	return func(c *Config) { c.KillGracePeriod = grace }
+}
+
+// WithMaxWorkers 设置最大并发执行数
+func WithMaxWorkers(n int) Option {
+	return func(c *Config) { c.MaxWorkers = n }
+}
+
+// WithPythonPath 设置 Python 解释器路径
+func WithPythonPath(path string) Option {
+	return func(c *Config) { c.PythonPath = path }
+}
+
+// WithPythonLibDir 把宿主机目录加入 Python 代码的导入路径，可多次调用，见 Config.PythonLibDirs
+func WithPythonLibDir(dirs ...string) Option {
+	return func(c *Config) { c.PythonLibDirs = append(c.PythonLibDirs, dirs...) }
+}
+
+// WithNodePath 设置 Node.js 解释器路径
+func WithNodePath(path string) Option {
+	return func(c *Config) { c.NodePath = path }
+}
+
+// WithNpmPath 设置安装 Node.js 依赖使用的 npm 路径
+func WithNpmPath(path string) Option {
+	return func(c *Config) { c.NpmPath = path }
+}
+
+// WithMemoryLimit 限制子进程的虚拟内存字节数，见 Config.MaxMemoryBytes
+func WithMemoryLimit(bytes int64) Option {
+	return func(c *Config) { c.MaxMemoryBytes = bytes }
+}
+
+// WithCPULimit 限制子进程消耗的CPU秒数，见 Config.MaxCPUSeconds
+func WithCPULimit(seconds int64) Option {
+	return func(c *Config) { c.MaxCPUSeconds = seconds }
+}
+
+// WithOutputLimit 限制 stdout 与 stderr 合计的字节数，见 Config.MaxOutputBytes
+func WithOutputLimit(bytes int64) Option {
+	return func(c *Config) { c.MaxOutputBytes = bytes }
+}
+
+// WithOutputRateLimit 限制平均输出速率为每秒 bytesPerSecond 字节，按 window 统计，见 Config.MaxOutputRate
+func WithOutputRateLimit(bytesPerSecond int64, window time.Duration) Option {
+	return func(c *Config) {
+		c.MaxOutputRate = bytesPerSecond
+		c.OutputRateWindow = window
+	}
+}
+
+// WithDenyNetwork 禁止子进程访问网络，见 Config.DenyNetwork
+func WithDenyNetwork() Option {
+	return func(c *Config) { c.DenyNetwork = true }
+}
+
+// WithBlockDNS 尽力阻止子进程解析域名，隔离程度弱于 WithDenyNetwork，见 Config.BlockDNS
+func WithBlockDNS() Option {
+	return func(c *Config) { c.BlockDNS = true }
+}
+
+// WithCache 启用结果缓存，见 Config.CacheSize
+func WithCache(size int, ttl time.Duration) Option {
+	return func(c *Config) {
+		c.CacheSize = size
+		c.CacheTTL = ttl
+	}
+}
+
+// WithPrelude 设置 language 的代码拼接在每次提交的代码前后的代码，为空表示不拼接，见 Config.PreludeCode
+func WithPrelude(language string, prelude string, postlude string) Option {
+	return func(c *Config) {
+		preludes := make(map[string]string, len(c.PreludeCode)+1)
+		for name, code := range c.PreludeCode {
+			preludes[name] = code
+		}
+		postludes := make(map[string]string, len(c.PostludeCode)+1)
+		for name, code := range c.PostludeCode {
+			postludes[name] = code
+		}
+		preludes[language], postludes[language] = prelude, postlude
+		c.PreludeCode, c.PostludeCode = preludes, postludes
+	}
+}
+
+// WithCompileCache 启用编译缓存，最多保留 size 份编译产物，ttl 为 0 表示不过期，见 Config.CompileCacheSize
+func WithCompileCache(size int, ttl time.Duration) Option {
+	return func(c *Config) {
+		c.CompileCacheSize = size
+		c.CompileCacheTTL = ttl
+	}
+}
+
+// WithInheritPath 让子进程沿用服务进程的 PATH，而不是只包含解释器所在目录与系统标准目录，见 Config.InheritPath
+func WithInheritPath() Option {
+	return func(c *Config) { c.InheritPath = true }
+}
+
+// WithUmask 设置子进程的 umask，见 Config.Umask
+func WithUmask(mask os.FileMode) Option {
+	return func(c *Config) { c.Umask = mask }
+}
+
+// WithLocale 选择面向用户的消息语言，见 Config.Locale
+func WithLocale(locale string) Option {
+	return func(c *Config) { c.Locale = locale }
+}
+
+// WithLocaleEnv 设置子进程的区域设置与时区，空字符串表示使用默认值，见 Config.Lang
+func WithLocaleEnv(lang string, timezone string) Option {
+	return func(c *Config) {
+		c.Lang = lang
+		c.Timezone = timezone
+	}
+}
+
+// WithQueueTimeout 设置工作池已满时的等待时长，见 Config.QueueTimeout
+func WithQueueTimeout(timeout time.Duration) Option {
+	return func(c *Config) { c.QueueTimeout = timeout }
+}
+
+// WithPriorityAging 设置每一级优先级相当于的排队时长，见 Config.PriorityAging
+func WithPriorityAging(aging time.Duration) Option {
+	return func(c *Config) { c.PriorityAging = aging }
+}
+
+// WithRateLimiter 按客户端限制执行频率，见 Config.RateLimiter
+func WithRateLimiter(limiter RateLimiter) Option {
+	return func(c *Config) { c.RateLimiter = limiter }
+}
+
+// WithRetry 设置临时故障的重试策略：最多执行 maxAttempts 次，第一次重试前等待 backoff，此后每次翻倍
+func WithRetry(maxAttempts int, backoff time.Duration) Option {
+	return func(c *Config) { c.Retry = &RetryPolicy{MaxAttempts: maxAttempts, Backoff: backoff} }
+}
+
+// WithCircuitBreaker 为各语言启用熔断：连续 threshold 次内部错误后熔断 cooldown，见 Config.CircuitBreaker
+func WithCircuitBreaker(threshold int, cooldown time.Duration) Option {
+	return func(c *Config) {
+		c.CircuitBreaker = &CircuitBreakerConfig{FailureThreshold: threshold, Cooldown: cooldown}
+	}
+}
+
+// WithTempDir 设置临时文件的创建位置，见 Config.TempDir
+func WithTempDir(dir string) Option {
+	return func(c *Config) { c.TempDir = dir }
+}
+
+// WithTempFS 设置创建与删除临时文件所用的文件系统，见 Config.TempFS
+func WithTempFS(fsys TempFS) Option {
+	return func(c *Config) { c.TempFS = fsys }
+}
+
+// WithPackageCacheDir 设置依赖环境的缓存目录，见 Config.PackageCacheDir
+func WithPackageCacheDir(dir string) Option {
+	return func(c *Config) { c.PackageCacheDir = dir }
+}
+
+// WithInstallTimeout 设置一次依赖安装的超时，见 Config.InstallTimeout
+func WithInstallTimeout(d time.Duration) Option {
+	return func(c *Config) { c.InstallTimeout = d }
+}
+
+// WithRunAsUser 让子进程以 user 运行，group 为空时使用其主组，见 Config.RunAsUser
+func WithRunAsUser(user string, group string) Option {
+	return func(c *Config) {
+		c.RunAsUser = user
+		c.RunAsGroup = group
+	}
+}
+
+// WithBackend 选择执行后端，见 Config.Backend
+func WithBackend(backend string) Option {
+	return func(c *Config) { c.Backend = backend }
+}
+
+// WithEventHandler 设置执行事件的回调，见 Config.OnEvent
+func WithEventHandler(handler func(ExecEvent)) Option {
+	return func(c *Config) { c.OnEvent = handler }
+}
+
+// WithTracerProvider 设置创建执行 span 所用的 TracerProvider，见 Config.TracerProvider
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(c *Config) { c.TracerProvider = provider }
+}
@@ -0,0 +1,187 @@
+package sandbox
+
+import (
+	"bytes"
+	"io"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// outputQuota 是 stdout 与 stderr 共享的输出字节配额，
+// 配额用尽后丢弃后续输出并调用 onExceed 终止进程
+type outputQuota struct {
+	mu        sync.Mutex
+	remaining int64
+	truncated bool
+	onExceed  func()
+}
+
+// cappedWriter 将输出写入 w，写入量受 quota 约束，保证内存占用有上限
+type cappedWriter struct {
+	quota *outputQuota
+	w     io.Writer
+}
+
+// Write 实现 io.Writer。超出配额的部分被丢弃，但仍返回 len(p)，
+// 避免 exec 的输出拷贝协程因写入错误提前退出
+func (w *cappedWriter) Write(p []byte) (int, error) {
+	w.quota.mu.Lock()
+	defer w.quota.mu.Unlock()
+
+	if w.quota.truncated {
+		return len(p), nil
+	}
+	if int64(len(p)) > w.quota.remaining {
+		w.w.Write(p[:w.quota.remaining])
+		w.quota.remaining = 0
+		w.quota.truncated = true
+		if w.quota.onExceed != nil {
+			w.quota.onExceed()
+		}
+		return len(p), nil
+	}
+	w.quota.remaining -= int64(len(p))
+	return w.w.Write(p)
+}
+
+// rateMonitor 统计 stdout 与 stderr 合计的输出量，同一个 window 内超过 limit 字节时
+// 丢弃后续输出并调用 onExceed 终止进程
+type rateMonitor struct {
+	mu       sync.Mutex
+	limit    int64
+	window   time.Duration
+	start    time.Time // 当前窗口的开始时间
+	written  int64     // 当前窗口内的输出字节数
+	exceeded bool
+	onExceed func()
+}
+
+// newRateMonitor 创建平均速率上限为 bytesPerSecond、按 window 统计的监视器
+func newRateMonitor(bytesPerSecond int64, window time.Duration, onExceed func()) *rateMonitor {
+	return &rateMonitor{
+		limit:    max(int64(float64(bytesPerSecond)*window.Seconds()), 1),
+		window:   window,
+		start:    time.Now(),
+		onExceed: onExceed,
+	}
+}
+
+// add 记录 n 字节的输出，速率已超限时返回 false
+func (m *rateMonitor) add(n int) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.exceeded {
+		return false
+	}
+	if now := time.Now(); now.Sub(m.start) >= m.window {
+		m.start, m.written = now, 0
+	}
+	m.written += int64(n)
+	if m.written > m.limit {
+		m.exceeded = true
+		m.onExceed()
+		return false
+	}
+	return true
+}
+
+// flooded 报告输出速率是否超出了上限
+func (m *rateMonitor) flooded() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.exceeded
+}
+
+// rateWriter 将输出写入 w，速率超限后丢弃写入但仍返回 len(p)，理由与 cappedWriter 相同
+type rateWriter struct {
+	monitor *rateMonitor
+	w       io.Writer
+}
+
+func (w *rateWriter) Write(p []byte) (int, error) {
+	if !w.monitor.add(len(p)) {
+		return len(p), nil
+	}
+	return w.w.Write(p)
+}
+
+// lockedBuffer 是可并发读写的缓冲区，用于在执行超时后读取子进程仍在写入的输出
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buf.String()
+}
+
+// teeWriter 返回同时写入 buf 与 sink 的 Writer，sink 为 nil 时只写入 buf
+func teeWriter(buf io.Writer, sink io.Writer) io.Writer {
+	if sink == nil {
+		return buf
+	}
+	return io.MultiWriter(buf, sink)
+}
+
+// switchWriter 把写入转发给当前的目标 Writer，目标为 nil 时丢弃写入。用于预热进程
+// （启动时还不知道输出属于哪次执行），以及在 ExecuteTo 返回后切断调用方的 Writer
+type switchWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// set 把之后的写入转发给 w
+func (s *switchWriter) set(w io.Writer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.w = w
+}
+
+func (s *switchWriter) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.w == nil {
+		return len(p), nil
+	}
+	return s.w.Write(p)
+}
+
+// ansiEscape 匹配 ANSI 转义序列：CSI 序列（ESC [ 参数字节 中间字节 结束字节，如颜色 "\x1b[31m"）、
+// 以 BEL 或 ESC \ 结束的 OSC 序列（如终端标题、超链接），以及其余的 ESC 序列（如 "\x1b7"、"\x1b(B"）
+var ansiEscape = regexp.MustCompile(`\x1b\[[0-?]*[ -/]*[@-~]|\x1b\][^\x07\x1b]*(?:\x07|\x1b\\)|\x1b[ -/]*[0-Z\\^-~]`)
+
+// stripANSI 删除结果输出中的 ANSI 转义序列，用于 ExecOptions.StripANSI
+func stripANSI(result ExecutionResult) ExecutionResult {
+	result.Output = ansiEscape.ReplaceAllString(result.Output, "")
+	result.Stderr = ansiEscape.ReplaceAllString(result.Stderr, "")
+	result.Error = ansiEscape.ReplaceAllString(result.Error, "")
+	return result
+}
+
+// sanitizeUTF8 把结果输出中的非法 UTF-8 字节替换为 U+FFFD，并在发生替换时设置 InvalidUTF8
+func sanitizeUTF8(result ExecutionResult) ExecutionResult {
+	for _, field := range []*string{&result.Output, &result.Stderr, &result.Error} {
+		if !utf8.ValidString(*field) {
+			*field = strings.ToValidUTF8(*field, "\uFFFD")
+			result.InvalidUTF8 = true
+		}
+	}
+	return result
+}
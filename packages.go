@@ -0,0 +1,249 @@
+package sandbox
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"maps"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultInstallTimeout 是未指定 InstallTimeout 时一次依赖安装的超时
+const defaultInstallTimeout = 5 * time.Minute
+
+// packagesCompleteMarker 在依赖安装成功后写入环境目录，没有它的目录是中断的安装，需要重建
+const packagesCompleteMarker = ".complete"
+
+// pipRequirement 匹配 pip 需求格式中只引用包索引的部分：名称、可选的 extras 与版本约束，如 "numpy>=1.26,<2"、
+// "requests[socks]==2.32.3"。直接引用（"name @ URL"）、URL、本地路径、版本库地址与环境标记都不匹配
+var pipRequirement = regexp.MustCompile(`^[A-Za-z0-9]([A-Za-z0-9._-]*[A-Za-z0-9])?` +
+	`(\[[A-Za-z0-9._-]+(,[A-Za-z0-9._-]+)*\])?` +
+	`((===|==|~=|!=|<=|>=|<|>)[A-Za-z0-9.*+!_-]+(,(===|==|~=|!=|<=|>=|<|>)[A-Za-z0-9.*+!_-]+)*)?$`)
+
+// npmSpec 匹配 npm 依赖中只引用包索引的部分：可选的作用域、名称与可选的版本范围或标签，如 "lodash@^4.17"、
+// "@types/node@20"。URL、tarball、本地路径、git 与 GitHub 简写（"user/repo"）以及别名（"npm:"）都不匹配
+var npmSpec = regexp.MustCompile(`^(@[A-Za-z0-9][A-Za-z0-9._~-]*/)?[A-Za-z0-9][A-Za-z0-9._~-]*(@[A-Za-z0-9.*^~<>=| +_-]+)?$`)
+
+// npmTarball 匹配 npm 当作本地 tarball 的名称
+var npmTarball = regexp.MustCompile(`(?i)\.(tgz|tar|tar\.gz)$`)
+
+// registrySpec 判断 spec 是否只引用 language 的包索引
+func registrySpec(language string, spec string) bool {
+	if language == "nodejs" {
+		return npmSpec.MatchString(spec) && !npmTarball.MatchString(spec)
+	}
+	// 版本约束两侧允许空格，如 "numpy >= 1.26"
+	return pipRequirement.MatchString(strings.ReplaceAll(spec, " ", ""))
+}
+
+// checkPackages 检查 language 的内置 Runner 能否安装 packages：需要开启 Config.AllowPackages，
+// 只支持 Python 与 Node.js 的非 Docker 后端，且每个依赖都必须是包索引中的名称与版本约束
+func (e *CodeExecutor) checkPackages(language string, packages []string) error {
+	if !e.allowPackages {
+		return e.sandboxError(ErrorKindInvalidRequest, MsgPackagesDisabled)
+	}
+	if e.backend == BackendDocker || (language != "python3" && language != "nodejs") {
+		return e.sandboxError(ErrorKindInvalidRequest, MsgPackagesUnsupported, language)
+	}
+	for _, pkg := range packages {
+		// 两个格式都要求以字母、数字或 "@" 开头，以 "-" 开头会被包管理器当作选项的名称不会通过
+		if !registrySpec(language, strings.TrimSpace(pkg)) {
+			return e.sandboxError(ErrorKindInvalidRequest, MsgInvalidPackage, pkg)
+		}
+	}
+	return nil
+}
+
+// packageSetKey 返回依赖集合的缓存键：与顺序和重复无关，并区分语言与解释器
+func packageSetKey(language string, interpreter string, packages []string) string {
+	sorted := slices.Clone(packages)
+	for i := range sorted {
+		sorted[i] = strings.TrimSpace(sorted[i])
+	}
+	slices.Sort(sorted)
+	sorted = slices.Compact(sorted)
+
+	h := sha256.New()
+	for _, s := range append([]string{language, interpreter}, sorted...) {
+		h.Write([]byte(s))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+// packageRoot 返回依赖环境的缓存目录
+func (e *CodeExecutor) packageRoot() string {
+	if e.packageDir != "" {
+		return e.packageDir
+	}
+	return filepath.Join(e.tempRoot(), "sandbox-packages")
+}
+
+// lockPackages 串行化同一个依赖环境的安装，返回解锁函数
+func (e *CodeExecutor) lockPackages(dir string) func() {
+	e.packageMu.Lock()
+	if e.packageLocks == nil {
+		e.packageLocks = make(map[string]*sync.Mutex)
+	}
+	lock, ok := e.packageLocks[dir]
+	if !ok {
+		lock = &sync.Mutex{}
+		e.packageLocks[dir] = lock
+	}
+	e.packageMu.Unlock()
+
+	lock.Lock()
+	return lock.Unlock
+}
+
+// venvPython 返回虚拟环境 dir 中的 Python 解释器
+func venvPython(dir string) string {
+	if runtime.GOOS == "windows" {
+		return filepath.Join(dir, "Scripts", "python.exe")
+	}
+	return filepath.Join(dir, "bin", "python")
+}
+
+// installPackages 准备安装了 opts.Packages 的依赖环境并返回其目录，已缓存时直接复用；依赖须已通过 checkPackages 检查。
+// 包管理器经 runInstallCommand 以运行用户的身份在沙箱中运行，超时由 InstallTimeout 控制。
+// 安装完成后环境的属主改回服务进程并设为只读，之后的执行只能读取。安装失败时返回描述失败的结果
+func (e *CodeExecutor) installPackages(ctx context.Context, language string, opts ExecOptions) (string, *ExecutionResult) {
+	interpreter, prefix := e.pythonInterpreter(opts.PythonVersion), "pip-"
+	if language == "nodejs" {
+		interpreter, prefix = e.nodePath, "npm-"
+	}
+	dir := filepath.Join(e.packageRoot(), prefix+packageSetKey(language, interpreter, opts.Packages))
+
+	unlock := e.lockPackages(dir)
+	defer unlock()
+	if _, err := os.Stat(filepath.Join(dir, packagesCompleteMarker)); err == nil {
+		return dir, nil
+	}
+
+	ctx, span := startPhase(ctx, "sandbox.install")
+	defer endPhase(span, nil)
+	ctx, cancel := context.WithTimeout(ctx, e.installTimeout)
+	defer cancel()
+
+	start := time.Now()
+	fail := func(result ExecutionResult) (string, *ExecutionResult) {
+		removeWithRetry(os.RemoveAll, dir)
+		if ctx.Err() == context.DeadlineExceeded {
+			result.Error = e.msg(MsgInstallTimeout, e.installTimeout.Round(time.Second).Seconds()) + "\n" + result.Error
+		}
+		// 安装日志只写入 Error，Output 留给代码本身的输出
+		failed := ExecutionResult{
+			Success:    false,
+			Error:      e.msg(MsgInstallFailed) + ":\n" + result.Output + result.Error,
+			ErrorKind:  ErrorKindInstallError,
+			ExitCode:   result.ExitCode,
+			DurationMs: time.Since(start).Milliseconds(),
+		}
+		return "", &failed
+	}
+
+	// 上次中断的安装可能留下了不完整的环境。环境目录由服务进程创建，runInstallCommand 把它交给运行用户
+	removeWithRetry(os.RemoveAll, dir)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fail(e.fail(ErrorKindInternal, MsgCreateTempDir, err))
+	}
+	install := e.pipInstall
+	if language == "nodejs" {
+		install = e.npmInstall
+	}
+	if result := install(ctx, interpreter, dir, opts.Packages); !result.Success {
+		return fail(result)
+	}
+	if err := e.sealPackages(dir); err != nil {
+		return fail(e.fail(ErrorKindInternal, MsgWriteFile, dir, err))
+	}
+	if err := os.WriteFile(filepath.Join(dir, packagesCompleteMarker), nil, 0o444); err != nil {
+		return fail(ExecutionResult{Error: err.Error(), ExitCode: -1})
+	}
+	return dir, nil
+}
+
+// runInstallCommand 以 dir 为工作目录运行包管理器 cmd：与用户代码一样切换运行用户、施加 bwrap 沙箱与资源限制，
+// 只保留网络访问。输出不受 MaxOutputBytes 限制，也不发送给实时输出
+func (e *CodeExecutor) runInstallCommand(ctx context.Context, cmd *exec.Cmd, dir string) ExecutionResult {
+	if err := e.prepareUserCommand(cmd, ExecOptions{WorkDir: dir, install: true}); err != nil {
+		return errorResultFrom(err)
+	}
+	result := e.runCommand(ctx, cmd, 0, nil, nil)
+	return e.annotateLimits(result, cmd.ProcessState)
+}
+
+// sealPackages 把安装好的依赖环境 dir 的属主改回服务进程并设为只读，
+// 以运行用户身份执行的代码无法篡改同一依赖集合的后续执行共享的环境
+func (e *CodeExecutor) sealPackages(dir string) error {
+	if e.credential != nil {
+		if err := chownTree(dir, &credential{uid: uint32(os.Geteuid()), gid: uint32(os.Getegid())}); err != nil {
+			return err
+		}
+	}
+	return shareTree(dir)
+}
+
+// withoutPackagePaths 去掉 args 中位于依赖缓存目录下的路径（如虚拟环境的解释器）。依赖环境由所有使用同一依赖集合的
+// 执行共享，已由 sealPackages 设为只读，不能当作本次执行的代码文件改变属主；bwrap 下由 opts.packageEnv 单独挂载
+func (e *CodeExecutor) withoutPackagePaths(args []string) []string {
+	root := e.packageRoot()
+	rest := make([]string, 0, len(args))
+	for _, arg := range args {
+		if rel, err := filepath.Rel(root, arg); err == nil && filepath.IsAbs(arg) && filepath.IsLocal(rel) {
+			continue
+		}
+		rest = append(rest, arg)
+	}
+	return rest
+}
+
+// pipInstall 用 interpreter 在 dir 创建虚拟环境并安装 packages。--only-binary=:all: 使 pip 只安装 wheel，
+// 不会为了构建源码包而执行其中的 setup.py
+func (e *CodeExecutor) pipInstall(ctx context.Context, interpreter string, dir string, packages []string) ExecutionResult {
+	venv := e.runInstallCommand(ctx, exec.CommandContext(ctx, interpreter, "-m", "venv", dir), dir)
+	if !venv.Success {
+		return venv
+	}
+	args := []string{"-m", "pip", "install", "--disable-pip-version-check", "--no-input", "--quiet",
+		"--no-cache-dir", "--only-binary=:all:", "--"}
+	return e.runInstallCommand(ctx, exec.CommandContext(ctx, venvPython(dir), append(args, packages...)...), dir)
+}
+
+// npmInstall 在 dir 生成 package.json 并用 npm 安装 packages 到 dir/node_modules。--ignore-scripts 使 npm
+// 不运行依赖的生命周期脚本；package.json 归服务进程所有，--no-save 与 --no-package-lock 使 npm 不改写它，
+// 下载缓存放在 dir 中，安装结束后删除
+func (e *CodeExecutor) npmInstall(ctx context.Context, _ string, dir string, packages []string) ExecutionResult {
+	manifest := `{"name": "sandbox-packages", "private": true}` + "\n"
+	if err := os.WriteFile(filepath.Join(dir, "package.json"), []byte(manifest), 0o644); err != nil {
+		return e.fail(ErrorKindInternal, MsgWriteFile, "package.json", err)
+	}
+	cache := filepath.Join(dir, ".npm-cache")
+	defer removeWithRetry(os.RemoveAll, cache)
+	args := []string{"install", "--prefix", dir, "--cache", cache, "--ignore-scripts", "--no-save", "--no-package-lock",
+		"--no-audit", "--no-fund", "--no-update-notifier", "--no-progress", "--loglevel=error", "--"}
+	return e.runInstallCommand(ctx, exec.CommandContext(ctx, e.npmPath, append(args, packages...)...), dir)
+}
+
+// withNodePath 返回把依赖环境的 node_modules 加入 NODE_PATH 的 opts 副本，不修改调用方的 Env
+func withNodePath(opts ExecOptions) ExecOptions {
+	modules := filepath.Join(opts.packageEnv, "node_modules")
+	env := maps.Clone(opts.Env)
+	if env == nil {
+		env = make(map[string]string, 1)
+	}
+	if existing := env["NODE_PATH"]; existing != "" {
+		modules += string(filepath.ListSeparator) + existing
+	}
+	env["NODE_PATH"] = modules
+	opts.Env = env
+	return opts
+}
@@ -0,0 +1,97 @@
+package sandbox
+
+import (
+	"context"
+	"os"
+	"testing"
+)
+
+func TestPipRequirement(t *testing.T) {
+	tests := []struct {
+		spec string
+		want bool
+	}{
+		{"requests", true},
+		{"numpy>=1.26,<2", true},
+		{"requests[socks]==2.32.3", true},
+		{"zope.interface", true},
+		{"Django~=4.2", true},
+		{"x @ https://example.com/evil.tar.gz", false},
+		{"x@https://example.com/evil.tar.gz", false},
+		{"https://example.com/evil.tar.gz", false},
+		{"git+https://example.com/repo.git", false},
+		{"./local", false},
+		{"/abs/pkg.whl", false},
+		{"file:///tmp/pkg", false},
+		{`pkg; python_version>"3"`, false},
+		{"-e .", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			if got := pipRequirement.MatchString(tt.spec); got != tt.want {
+				t.Errorf("pipRequirement.MatchString(%q) = %v，期望 %v", tt.spec, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNpmSpec(t *testing.T) {
+	tests := []struct {
+		spec string
+		want bool
+	}{
+		{"lodash", true},
+		{"zod@3", true},
+		{"lodash@^4.17.21", true},
+		{"@types/node@20", true},
+		{"react@>=18 <19", true},
+		{"typescript@next", true},
+		{"https://example.com/evil.tgz", false},
+		{"git+https://example.com/repo.git", false},
+		{"github:user/repo", false},
+		{"user/repo", false},
+		{"x@file:../evil", false},
+		{"x@npm:evil", false},
+		{"evil.tgz", false},
+		{"./local", false},
+		{"--registry=https://example.com", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			if got := registrySpec("nodejs", tt.spec); got != tt.want {
+				t.Errorf("registrySpec(nodejs, %q) = %v，期望 %v", tt.spec, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPackagesRejected(t *testing.T) {
+	tests := []struct {
+		name     string
+		allow    bool
+		language string
+		packages []string
+	}{
+		{"not allowed", false, "python3", []string{"requests"}},
+		{"direct reference", true, "python3", []string{"x @ https://example.com/evil.tar.gz"}},
+		{"vcs", true, "python3", []string{"git+https://example.com/repo.git"}},
+		{"local path", true, "python3", []string{"requests", "../pkg"}},
+		{"option", true, "python3", []string{"--index-url=https://example.com"}},
+		{"npm not allowed", false, "nodejs", []string{"lodash"}},
+		{"npm tarball", true, "nodejs", []string{"https://example.com/evil.tgz"}},
+		{"npm git", true, "nodejs", []string{"lodash", "github:user/repo"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cacheDir := t.TempDir()
+			e := newTestExecutor(t, tt.language, Config{AllowPackages: tt.allow, PackageCacheDir: cacheDir})
+			result := e.ExecuteContextWithOptions(context.Background(), `print("ran")`, tt.language, ExecOptions{Packages: tt.packages})
+			if result.ErrorKind != ErrorKindInvalidRequest {
+				t.Fatalf("ErrorKind = %q，期望 %q（%s）", result.ErrorKind, ErrorKindInvalidRequest, result.Error)
+			}
+			if entries, _ := os.ReadDir(cacheDir); len(entries) > 0 {
+				t.Errorf("被拒绝的依赖不应开始安装，缓存目录中有 %d 项", len(entries))
+			}
+		})
+	}
+}
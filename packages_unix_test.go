@@ -0,0 +1,197 @@
+//go:build unix
+
+package sandbox
+
+import (
+	"os"
+	"os/user"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// fakePython 模拟 Python 解释器：-m venv 把自身复制为虚拟环境的解释器，-m pip 在工作目录（依赖环境）中
+// 记录运行用户、参数与 RES_OPTIONS，运行代码时输出 ran。PIP_SLEEP 大于 0 时 pip 先等待该秒数
+const fakePython = `#!/bin/sh
+case "$1" in
+--version) echo "Python 3.12.0"; exit 0 ;;
+-m)
+	case "$2" in
+	venv) mkdir -p "$3/bin" && cp "$0" "$3/bin/python"; exit $? ;;
+	pip) sleep "${PIP_SLEEP:-0}"; echo "$(id -u) RES_OPTIONS=$RES_OPTIONS $*" > pip.log; exit 0 ;;
+	esac ;;
+esac
+echo "ran RES_OPTIONS=$RES_OPTIONS"
+`
+
+// sharedTempDir 返回其他用户也能进入的临时目录
+func sharedTempDir(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	for _, path := range []string{filepath.Dir(dir), dir} {
+		if err := os.Chmod(path, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestPackagesInstallSandboxed(t *testing.T) {
+	root := sharedTempDir(t)
+	python := filepath.Join(root, "python")
+	if err := os.WriteFile(python, []byte(fakePython), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	uid := os.Getuid()
+	config := Config{AllowPackages: true, PythonPath: python, PackageCacheDir: filepath.Join(root, "packages"), BlockDNS: true}
+	if os.Geteuid() == 0 {
+		nobody, err := user.Lookup("nobody")
+		if err != nil {
+			t.Skip("以 root 运行时需要 nobody 用户")
+		}
+		config.RunAsUser = "nobody"
+		uid = mustAtoi(t, nobody.Uid)
+	}
+	e := newTestExecutor(t, "python3", config)
+
+	result := e.ExecuteWithOptions(`print("ran")`, "python3", ExecOptions{Packages: []string{"requests>=2"}})
+	if !result.Success {
+		t.Fatalf("执行失败: %s", result.Error)
+	}
+	if !strings.Contains(result.Output, "RES_OPTIONS=attempts:0") {
+		t.Errorf("代码运行时应注入 BlockDNS 的变量，输出 %q", result.Output)
+	}
+	logs, _ := filepath.Glob(filepath.Join(config.PackageCacheDir, "*", "pip.log"))
+	if len(logs) != 1 {
+		t.Fatalf("找到 %d 份 pip 日志，期望 1 份", len(logs))
+	}
+	data, err := os.ReadFile(logs[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	fields := strings.Fields(string(data))
+	if mustAtoi(t, fields[0]) != uid {
+		t.Errorf("pip 以 uid %s 运行，期望 %d", fields[0], uid)
+	}
+	if fields[1] != "RES_OPTIONS=" {
+		t.Errorf("安装依赖时不应注入 BlockDNS 的变量，得到 %s", fields[1])
+	}
+	if !strings.Contains(string(data), "--only-binary=:all:") {
+		t.Errorf("pip 参数 %q 中没有 --only-binary=:all:", data)
+	}
+
+	// 安装好的环境归服务进程所有且只读，代码无法篡改
+	env := filepath.Dir(logs[0])
+	filepath.Walk(env, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.Mode().Perm()&0o222 != 0 && !info.IsDir() {
+			t.Errorf("%s 的权限为 %v，期望只读", path, info.Mode().Perm())
+		}
+		if owner := fileOwner(info); owner != os.Geteuid() {
+			t.Errorf("%s 的属主为 %d，期望服务进程 %d", path, owner, os.Geteuid())
+		}
+		return nil
+	})
+}
+
+// fakeNpm 模拟 npm install：在工作目录（依赖环境）中创建 node_modules 与下载缓存，并记录运行用户与参数
+const fakeNpm = `#!/bin/sh
+mkdir -p node_modules .npm-cache && echo "$(id -u) $*" > npm.log
+`
+
+func TestNpmInstallSandboxed(t *testing.T) {
+	root := sharedTempDir(t)
+	npm := filepath.Join(root, "npm")
+	if err := os.WriteFile(npm, []byte(fakeNpm), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	uid := os.Getuid()
+	config := Config{AllowPackages: true, NpmPath: npm, PackageCacheDir: filepath.Join(root, "packages")}
+	if os.Geteuid() == 0 {
+		nobody, err := user.Lookup("nobody")
+		if err != nil {
+			t.Skip("以 root 运行时需要 nobody 用户")
+		}
+		config.RunAsUser = "nobody"
+		uid = mustAtoi(t, nobody.Uid)
+	}
+	e := newTestExecutor(t, "nodejs", config)
+
+	result := e.ExecuteWithOptions(`console.log(process.env.NODE_PATH)`, "nodejs", ExecOptions{Packages: []string{"lodash@^4"}})
+	if !result.Success {
+		t.Fatalf("执行失败: %s", result.Error)
+	}
+	logs, _ := filepath.Glob(filepath.Join(config.PackageCacheDir, "*", "npm.log"))
+	if len(logs) != 1 {
+		t.Fatalf("找到 %d 份 npm 日志，期望 1 份", len(logs))
+	}
+	data, err := os.ReadFile(logs[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	fields := strings.Fields(string(data))
+	if mustAtoi(t, fields[0]) != uid {
+		t.Errorf("npm 以 uid %s 运行，期望 %d", fields[0], uid)
+	}
+	for _, arg := range []string{"--ignore-scripts", "--no-save"} {
+		if !slices.Contains(fields, arg) {
+			t.Errorf("npm 参数 %q 中没有 %s", data, arg)
+		}
+	}
+	env := filepath.Dir(logs[0])
+	if _, err := os.Stat(filepath.Join(env, ".npm-cache")); !os.IsNotExist(err) {
+		t.Errorf("安装结束后应删除 npm 的下载缓存: %v", err)
+	}
+	if info, err := os.Stat(filepath.Join(env, "node_modules")); err != nil || fileOwner(info) != os.Geteuid() {
+		t.Errorf("node_modules 应归服务进程所有: %v", err)
+	}
+}
+
+func TestPackagesInstallHoldsWorker(t *testing.T) {
+	root := sharedTempDir(t)
+	python := filepath.Join(root, "python")
+	if err := os.WriteFile(python, []byte(fakePython), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PIP_SLEEP", "2")
+	e := newTestExecutor(t, "python3", Config{
+		AllowPackages: true, PythonPath: python, PackageCacheDir: filepath.Join(root, "packages"),
+		MaxWorkers: 1, QueueTimeout: -1, EnvAllowlist: []string{"PATH", "PIP_SLEEP"},
+	})
+
+	done := make(chan ExecutionResult, 1)
+	go func() {
+		done <- e.ExecuteWithOptions(`print("ran")`, "python3", ExecOptions{Packages: []string{"requests"}})
+	}()
+	for deadline := time.Now().Add(5 * time.Second); e.Stats().InFlight == 0; time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("安装没有占用工作池令牌")
+		}
+	}
+	if result := e.Execute(`print("ran")`, "python3"); result.ErrorKind != ErrorKindBusy {
+		t.Errorf("安装期间的执行 ErrorKind = %q，期望 %q", result.ErrorKind, ErrorKindBusy)
+	}
+	if result := <-done; !result.Success {
+		t.Errorf("安装依赖的执行失败: %s", result.Error)
+	}
+}
+
+func mustAtoi(t *testing.T, s string) int {
+	t.Helper()
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+// fileOwner 返回文件的属主 uid
+func fileOwner(info os.FileInfo) int {
+	return int(info.Sys().(*syscall.Stat_t).Uid)
+}
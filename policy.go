@@ -0,0 +1,163 @@
+package sandbox
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// ImportPolicy 是 Python 代码的导入策略，在代码运行之前通过静态分析检查。
+// 条目按模块路径前缀匹配："os" 同时匹配 os 与 os.path。
+// Allow 非空时只允许其中的模块，Deny 中的模块始终禁止。
+// 静态分析无法发现 __import__、importlib 等动态导入，应与其他隔离手段配合使用
+type ImportPolicy struct {
+	Allow []string
+	Deny  []string
+}
+
+// pythonImportAnalyzer 从标准输入读取源代码的 JSON 数组，以 JSON 数组输出它们导入的模块。
+// "from a import b" 记为 a.b，使前缀规则同时覆盖子模块与模块属性；相对导入被忽略。
+// 无法解析的代码被跳过，交由解释器在运行或导入它时报告语法错误
+const pythonImportAnalyzer = `
+import ast, json, sys
+names = []
+for source in json.load(sys.stdin):
+    try:
+        tree = ast.parse(source)
+    except (SyntaxError, ValueError):
+        continue
+    for node in ast.walk(tree):
+        if isinstance(node, ast.Import):
+            names.extend(alias.name for alias in node.names)
+        elif isinstance(node, ast.ImportFrom) and node.level == 0 and node.module:
+            names.append(node.module)
+            names.extend(node.module + "." + alias.name for alias in node.names if alias.name != "*")
+print(json.dumps(names))
+`
+
+// projectPythonSources 返回项目目录 dir 中全部 .py 文件的内容，以及项目顶层的模块名：
+// 顶层的 .py 文件去掉扩展名，以及顶层的目录（包）
+func projectPythonSources(dir string) (sources []string, local map[string]bool, err error) {
+	local = make(map[string]bool)
+	err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == dir {
+			return nil
+		}
+		topLevel := filepath.Dir(path) == dir
+		if d.IsDir() {
+			if topLevel {
+				local[d.Name()] = true
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() || filepath.Ext(path) != ".py" {
+			return nil
+		}
+		if topLevel {
+			local[strings.TrimSuffix(d.Name(), ".py")] = true
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		sources = append(sources, string(content))
+		return nil
+	})
+	return sources, local, err
+}
+
+// matchesModule 判断 module 是否等于 prefix 或是其子模块
+func matchesModule(module string, prefix string) bool {
+	return module == prefix || strings.HasPrefix(module, prefix+".")
+}
+
+// violations 返回 modules 中违反策略的模块，保持首次出现的顺序；
+// 已报告模块的子模块（如 from subprocess import run 产生的 subprocess.run）不再重复报告
+func (p *ImportPolicy) violations(modules []string) []string {
+	var denied []string
+	for _, module := range modules {
+		if p.allows(module) || reported(denied, module) {
+			continue
+		}
+		denied = append(denied, module)
+	}
+	return denied
+}
+
+func reported(denied []string, module string) bool {
+	for _, prefix := range denied {
+		if matchesModule(module, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *ImportPolicy) allows(module string) bool {
+	for _, prefix := range p.Deny {
+		if matchesModule(module, prefix) {
+			return false
+		}
+	}
+	if len(p.Allow) == 0 {
+		return true
+	}
+	for _, prefix := range p.Allow {
+		if matchesModule(module, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkPythonImports 用 interpreter 解析代码中的导入语句并按 Config.PythonImportPolicy 检查，
+// 解析过程不会执行用户代码。多文件项目检查工作目录中的全部 .py 文件，入口文件导入的辅助模块同样受策略约束；
+// 导入项目自身的模块（与工作目录顶层的 .py 文件或目录同名）不算违规。
+// 存在违规导入时返回 ErrorKindPolicyViolation 的失败结果，否则返回 nil
+func (e *CodeExecutor) checkPythonImports(ctx context.Context, interpreter string, code string, opts ExecOptions) *ExecutionResult {
+	sources, local := []string{code}, map[string]bool(nil)
+	if opts.sourceFile != "" {
+		var err error
+		if sources, local, err = projectPythonSources(opts.WorkDir); err != nil {
+			result := e.fail(ErrorKindInternal, MsgPolicyCheck, err)
+			return &result
+		}
+	}
+	input, err := json.Marshal(sources)
+	if err != nil {
+		result := e.fail(ErrorKindInternal, MsgPolicyCheck, err)
+		return &result
+	}
+
+	cmd := exec.CommandContext(ctx, interpreter, "-I", "-c", pythonImportAnalyzer)
+	cmd.Stdin = bytes.NewReader(input)
+	analyzed := e.runCommand(ctx, cmd, 0, nil, nil)
+	if !analyzed.Success {
+		result := e.fail(ErrorKindInternal, MsgPolicyCheck, strings.TrimSpace(analyzed.Error))
+		return &result
+	}
+
+	var modules []string
+	if err := json.Unmarshal([]byte(analyzed.Output), &modules); err != nil {
+		result := e.fail(ErrorKindInternal, MsgPolicyCheck, err)
+		return &result
+	}
+	modules = slices.DeleteFunc(modules, func(module string) bool {
+		top, _, _ := strings.Cut(module, ".")
+		return local[top]
+	})
+	if denied := e.importPolicy.violations(modules); len(denied) > 0 {
+		result := e.fail(ErrorKindPolicyViolation, MsgImportDenied, strings.Join(denied, ", "))
+		return &result
+	}
+	return nil
+}
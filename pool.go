@@ -0,0 +1,173 @@
+package sandbox
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"time"
+)
+
+// errPoolBusy 表示在 QueueTimeout 内没有等到空闲的工作池令牌
+var errPoolBusy = errors.New("worker pool busy")
+
+// Priority 是执行在工作池中排队的优先级，数值越大越先获得空闲令牌，见 ExecOptions.Priority
+type Priority int
+
+const (
+	PriorityLow    Priority = -1 // 后台任务，如批量评测
+	PriorityNormal Priority = 0  // 默认优先级
+	PriorityHigh   Priority = 1  // 交互式请求
+)
+
+// defaultPriorityAging 是未指定 PriorityAging 时每一级优先级相当于的排队时长
+const defaultPriorityAging = 10 * time.Second
+
+// poolWaiter 是一个等待工作池令牌的执行
+type poolWaiter struct {
+	deadline time.Time // 入队时间减去优先级折算的排队时长，越早越先获得令牌
+	seq      uint64    // 入队顺序，deadline 相同时先入队者优先
+	index    int       // 在 waitQueue 中的位置，出队后为 -1
+}
+
+// waitQueue 是按 deadline 排序的等待者小顶堆，实现 heap.Interface
+type waitQueue []*poolWaiter
+
+func (q waitQueue) Len() int { return len(q) }
+
+func (q waitQueue) Less(i, j int) bool {
+	if !q[i].deadline.Equal(q[j].deadline) {
+		return q[i].deadline.Before(q[j].deadline)
+	}
+	return q[i].seq < q[j].seq
+}
+
+func (q waitQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index, q[j].index = i, j
+}
+
+func (q *waitQueue) Push(x any) {
+	w := x.(*poolWaiter)
+	w.index = len(*q)
+	*q = append(*q, w)
+}
+
+func (q *waitQueue) Pop() any {
+	old := *q
+	w := old[len(old)-1]
+	old[len(old)-1] = nil
+	w.index = -1
+	*q = old[:len(old)-1]
+	return w
+}
+
+// acquireWorker 以 priority 占用一个工作池令牌，令牌已满时按 queueTimeout 等待其他执行释放：
+// 为 0 时一直等待直到 ctx 结束，为负数时立即返回 errPoolBusy。
+// 空闲令牌总是交给排在最前面的等待者：优先级每高一级相当于多排队了 priorityAging，
+// 因此低优先级的执行等待足够久后会排到新来的高优先级执行之前，不会一直饿死
+func (e *CodeExecutor) acquireWorker(ctx context.Context, priority Priority) error {
+	e.mu.Lock()
+	w := &poolWaiter{
+		deadline: time.Now().Add(-time.Duration(priority) * e.priorityAging),
+		seq:      e.waiterSeq,
+	}
+	e.waiterSeq++
+	heap.Push(&e.waiters, w)
+	e.mu.Unlock()
+
+	// leave 在放弃等待时出队，并唤醒其他等待者，排在后面的执行可能因此成为队首
+	leave := func() {
+		e.mu.Lock()
+		defer e.mu.Unlock()
+
+		heap.Remove(&e.waiters, w.index)
+		e.notifyPoolLocked()
+	}
+
+	var expired <-chan time.Time
+	for {
+		e.mu.Lock()
+		full := e.activeWorkers >= e.maxWorkers
+		if !full && e.waiters[0] == w {
+			heap.Pop(&e.waiters)
+			e.activeWorkers++
+			// 可能还有空闲令牌留给新的队首
+			e.notifyPoolLocked()
+			e.mu.Unlock()
+			return nil
+		}
+		changed := e.poolChanged
+		e.mu.Unlock()
+
+		// 有空闲令牌但不在队首时，队首的执行拿到令牌后会再次唤醒
+		if e.queueTimeout < 0 && full {
+			leave()
+			return errPoolBusy
+		}
+		if e.queueTimeout > 0 && expired == nil {
+			timer := time.NewTimer(e.queueTimeout)
+			defer timer.Stop()
+			expired = timer.C
+		}
+		select {
+		case <-changed:
+		case <-expired:
+			leave()
+			return errPoolBusy
+		case <-ctx.Done():
+			leave()
+			return ctx.Err()
+		}
+	}
+}
+
+// releaseWorker 归还 acquireWorker 占用的令牌
+func (e *CodeExecutor) releaseWorker() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.activeWorkers--
+	e.notifyPoolLocked()
+}
+
+// notifyPoolLocked 唤醒所有等待令牌的执行重新检查，调用方须持有 e.mu
+func (e *CodeExecutor) notifyPoolLocked() {
+	close(e.poolChanged)
+	e.poolChanged = make(chan struct{})
+}
+
+// SetMaxWorkers 在运行时调整最大并发执行数，n 小于 1 时按 1 处理。
+// 扩容后排队中的执行立即开始；缩容不会中断正在运行的执行，
+// 只是在运行数降到新的上限以下之前不再开始新的执行。可与执行并发调用
+func (e *CodeExecutor) SetMaxWorkers(n int) {
+	if n < 1 {
+		n = 1
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.maxWorkers = n
+	e.notifyPoolLocked()
+}
+
+// ActiveCount 返回当前占用工作池令牌、正在运行的执行数
+func (e *CodeExecutor) ActiveCount() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return e.activeWorkers
+}
+
+// QueuedCount 返回正在等待工作池令牌的执行数
+func (e *CodeExecutor) QueuedCount() int {
+	return int(e.queued.Load())
+}
+
+// MaxWorkers 返回当前的最大并发执行数，与 ActiveCount 一起可用于计算工作池的利用率
+func (e *CodeExecutor) MaxWorkers() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return e.maxWorkers
+}
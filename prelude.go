@@ -0,0 +1,81 @@
+package sandbox
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// snippetFiles 匹配错误栈与编译错误中指向用户代码文件的名称：预热进程的 "<sandbox>"、从标准输入读取时的
+// "<stdin>"（Node.js 为 "[stdin]"）、RewriteTempPaths 替换后的 "<snippet>.ext"、临时文件 "python-123.py"
+// 以及编译型语言与 ExecuteReader 使用的 "main.ext"
+const snippetFiles = `<sandbox>|<stdin>|\[stdin\]|<snippet>\.\w+|\b(?:[a-z]+-\d+|main)\.\w+`
+
+// snippetLine 匹配 Python 的 `File "name", line N` 与其余语言的 `name:N`，第 1 组是行号之前的部分
+var snippetLine = snippetLinePattern(snippetFiles)
+
+// excerptLine 匹配 gcc 与 rustc 在编译错误中引用源代码时的行号栏 "  12 | "，第 1 组是行号之前的缩进
+var excerptLine = regexp.MustCompile(`(?m)^( *)(\d+)( \| )`)
+
+func snippetLinePattern(files string) *regexp.Regexp {
+	return regexp.MustCompile(`((?:` + files + `)(?:", line |:))(\d+)`)
+}
+
+// wrapCode 把 Config.PreludeCode 与 Config.PostludeCode 中 language 的代码拼接在 code 前后，
+// 返回拼接后的代码以及前置代码占用的行数，没有配置时原样返回 code
+func (e *CodeExecutor) wrapCode(language string, code string) (string, int) {
+	prelude, postlude := e.preludes[language], e.postludes[language]
+	if prelude == "" && postlude == "" {
+		return code, 0
+	}
+	var b strings.Builder
+	for _, part := range []string{prelude, code, postlude} {
+		if part == "" {
+			continue
+		}
+		b.WriteString(part)
+		if !strings.HasSuffix(part, "\n") {
+			b.WriteByte('\n')
+		}
+	}
+	offset := strings.Count(prelude, "\n")
+	if prelude != "" && !strings.HasSuffix(prelude, "\n") {
+		offset++
+	}
+	return b.String(), offset
+}
+
+// unwrapLines 把结果中指向用户代码文件第 offset+1 到 offset+lines 行的行号减去 offset，
+// 使其对应用户提交的代码；指向前置或后置代码的行号保持不变。code 是拼接后实际执行的代码
+func unwrapLines(result ExecutionResult, language string, code string, offset int, lines int) ExecutionResult {
+	pattern := snippetLine
+	// Java 的源文件以代码中的类名命名
+	if language == "java" {
+		pattern = snippetLinePattern(snippetFiles + `|\b` + regexp.QuoteMeta(javaClassName(code)) + `\.java`)
+	}
+	shift := func(pattern *regexp.Regexp, s string) string {
+		return pattern.ReplaceAllStringFunc(s, func(match string) string {
+			groups := pattern.FindStringSubmatch(match)
+			n, err := strconv.Atoi(groups[2])
+			if err != nil || n <= offset || n > offset+lines {
+				return match
+			}
+			return groups[1] + strconv.Itoa(n-offset) + strings.Join(groups[3:], "")
+		})
+	}
+	result.Output, result.Stderr = shift(pattern, result.Output), shift(pattern, result.Stderr)
+	// 行号栏只出现在编译错误中，不改写程序自身的输出
+	result.Error = shift(excerptLine, shift(pattern, result.Error))
+	return result
+}
+
+// codeLines 返回 code 的行数，末尾的换行不计为新的一行
+func codeLines(code string) int {
+	if code == "" {
+		return 0
+	}
+	if strings.HasSuffix(code, "\n") {
+		return strings.Count(code, "\n")
+	}
+	return strings.Count(code, "\n") + 1
+}
@@ -0,0 +1,46 @@
+//go:build !unix
+
+package sandbox
+
+import (
+	"os"
+	"os/exec"
+	"time"
+)
+
+// killSignal 为空表示该平台上进程不是被信号终止的
+const killSignal = ""
+
+// warmSupported 为 false：该平台不支持 ExtraFiles，无法向预热进程传递代码
+const warmSupported = false
+
+// setupProcessGroup 在不支持进程组与信号的平台上保持 exec.CommandContext 的默认行为，
+// 即立即终止直接启动的进程，grace 不起作用
+func setupProcessGroup(cmd *exec.Cmd, grace time.Duration) (stop func()) {
+	return func() {}
+}
+
+// killProcessGroup 终止 cmd 直接启动的进程
+func killProcessGroup(cmd *exec.Cmd) {
+	cmd.Process.Kill()
+}
+
+// cpuLimitExceeded 在不支持 rlimit 的平台上始终返回 false
+func cpuLimitExceeded(state *os.ProcessState, limit time.Duration) bool {
+	return false
+}
+
+// killedBySIGKILL 在没有信号的平台上始终返回 false
+func killedBySIGKILL(state *os.ProcessState) bool {
+	return false
+}
+
+// peakRSS 在不支持 rusage 的平台上始终返回 0
+func peakRSS(state *os.ProcessState) int64 {
+	return 0
+}
+
+// terminationSignal 在没有信号的平台上始终返回空字符串
+func terminationSignal(state *os.ProcessState) string {
+	return ""
+}
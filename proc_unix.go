@@ -0,0 +1,144 @@
+//go:build unix
+
+package sandbox
+
+import (
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// killSignal 是进程在宽限期后仍未退出时最终发给进程组的信号名
+const killSignal = "SIGKILL"
+
+// warmSupported 报告能否通过 ExtraFiles 向预热进程传递代码
+const warmSupported = true
+
+// signalNames 是常见信号的名称，其余信号以编号表示
+var signalNames = map[syscall.Signal]string{
+	syscall.SIGHUP:  "SIGHUP",
+	syscall.SIGINT:  "SIGINT",
+	syscall.SIGQUIT: "SIGQUIT",
+	syscall.SIGILL:  "SIGILL",
+	syscall.SIGTRAP: "SIGTRAP",
+	syscall.SIGABRT: "SIGABRT",
+	syscall.SIGBUS:  "SIGBUS",
+	syscall.SIGFPE:  "SIGFPE",
+	syscall.SIGKILL: "SIGKILL",
+	syscall.SIGUSR1: "SIGUSR1",
+	syscall.SIGSEGV: "SIGSEGV",
+	syscall.SIGUSR2: "SIGUSR2",
+	syscall.SIGPIPE: "SIGPIPE",
+	syscall.SIGALRM: "SIGALRM",
+	syscall.SIGTERM: "SIGTERM",
+	syscall.SIGXCPU: "SIGXCPU",
+	syscall.SIGXFSZ: "SIGXFSZ",
+	syscall.SIGSYS:  "SIGSYS",
+}
+
+// setupProcessGroup 让子进程运行在独立的进程组中。ctx 到期时先向整个进程组发送 SIGTERM，
+// 让进程有机会写出缓冲的输出、清理自己的临时文件，grace 后仍未退出则发送 SIGKILL，
+// 连同其派生的子进程一并终止；grace 不大于 0 时直接发送 SIGKILL。
+// 返回的 stop 须在 Wait 返回后调用：主进程已经退出而宽限期未到时，立即杀死进程组中残留的进程
+func setupProcessGroup(cmd *exec.Cmd, grace time.Duration) (stop func()) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Setpgid = true
+
+	var (
+		mu     sync.Mutex
+		timer  *time.Timer
+		exited bool
+	)
+	// 负的 pid 表示整个进程组
+	killGroup := func() {
+		mu.Lock()
+		defer mu.Unlock()
+		if !exited {
+			syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+		}
+	}
+	cmd.Cancel = func() error {
+		if grace <= 0 {
+			return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+		}
+		mu.Lock()
+		if timer == nil {
+			timer = time.AfterFunc(grace, killGroup)
+		}
+		mu.Unlock()
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGTERM)
+	}
+	return func() {
+		mu.Lock()
+		defer mu.Unlock()
+		if timer != nil && timer.Stop() {
+			syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+		}
+		exited = true
+	}
+}
+
+// killProcessGroup 立即向 cmd 所在的进程组发送 SIGKILL，不经过 SIGTERM 宽限期，用于输出超限等场合
+func killProcessGroup(cmd *exec.Cmd) {
+	syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+}
+
+// cpuLimitExceeded 判断进程是否因超出 RLIMIT_CPU 而被内核终止：
+// 软限制触发 SIGXCPU，硬限制触发 SIGKILL（此时 CPU 时间必然已达到上限）
+func cpuLimitExceeded(state *os.ProcessState, limit time.Duration) bool {
+	status, ok := state.Sys().(syscall.WaitStatus)
+	if !ok || !status.Signaled() {
+		return false
+	}
+	switch status.Signal() {
+	case syscall.SIGXCPU:
+		return true
+	case syscall.SIGKILL:
+		return state.UserTime()+state.SystemTime() >= limit
+	}
+	return false
+}
+
+// killedBySIGKILL 判断进程是否被 SIGKILL 终止。执行器自身只在超时、取消或输出超限时发送 SIGKILL，
+// 除此之外的 SIGKILL 通常来自内核的 OOM killer（包括 cgroup 的内存上限）
+func killedBySIGKILL(state *os.ProcessState) bool {
+	status, ok := state.Sys().(syscall.WaitStatus)
+	return ok && status.Signaled() && status.Signal() == syscall.SIGKILL
+}
+
+// peakRSS 返回进程的峰值常驻内存（字节），无法获取时返回 0。
+// ru_maxrss 在 macOS 上以字节为单位，在 Linux 与各 BSD 上以 KiB 为单位
+func peakRSS(state *os.ProcessState) int64 {
+	if state == nil {
+		return 0
+	}
+	usage, ok := state.SysUsage().(*syscall.Rusage)
+	if !ok {
+		return 0
+	}
+	if runtime.GOOS == "darwin" || runtime.GOOS == "ios" {
+		return int64(usage.Maxrss)
+	}
+	return int64(usage.Maxrss) * 1024
+}
+
+// terminationSignal 返回终止进程的信号名，进程正常退出或未启动时返回空字符串
+func terminationSignal(state *os.ProcessState) string {
+	if state == nil {
+		return ""
+	}
+	status, ok := state.Sys().(syscall.WaitStatus)
+	if !ok || !status.Signaled() {
+		return ""
+	}
+	if name, ok := signalNames[status.Signal()]; ok {
+		return name
+	}
+	return "SIG" + strconv.Itoa(int(status.Signal()))
+}
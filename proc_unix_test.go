@@ -0,0 +1,73 @@
+//go:build unix
+
+package sandbox
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// spawnAndHang 启动一个子进程并打印其 PID，然后一直等待直到被终止
+const spawnAndHang = `import subprocess, sys, time
+child = subprocess.Popen(%s)
+print(child.pid, flush=True)
+time.sleep(60)
+`
+
+// processAlive 判断 pid 对应的进程是否仍在运行，已退出但尚未被回收的僵尸进程视为已结束
+func processAlive(pid int) bool {
+	if err := syscall.Kill(pid, 0); errors.Is(err, syscall.ESRCH) {
+		return false
+	}
+	stat, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return true
+	}
+	// 状态字段紧跟在括号括起的进程名之后
+	if i := bytes.LastIndexByte(stat, ')'); i >= 0 && i+2 < len(stat) {
+		return stat[i+2] != 'Z'
+	}
+	return true
+}
+
+func TestTimeoutKillsProcessGroup(t *testing.T) {
+	tests := []struct {
+		name  string
+		grace time.Duration
+		child string
+	}{
+		{"sigkill", -1, `["sleep", "60"]`},
+		{"grace period", 200 * time.Millisecond, `["sleep", "60"]`},
+		{"child ignores SIGTERM", 200 * time.Millisecond,
+			`[sys.executable, "-c", "import signal, time; signal.signal(signal.SIGTERM, signal.SIG_IGN); time.sleep(60)"]`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newTestExecutor(t, "python3", Config{KillGracePeriod: tt.grace})
+			result := e.ExecuteWithOptions(fmt.Sprintf(spawnAndHang, tt.child), "python3", ExecOptions{Timeout: time.Second})
+			if result.ErrorKind != ErrorKindTimeout {
+				t.Fatalf("ErrorKind = %q，期望 %q（%s）", result.ErrorKind, ErrorKindTimeout, result.Error)
+			}
+			pid, err := strconv.Atoi(strings.TrimSpace(result.Output))
+			if err != nil {
+				t.Fatalf("无法从输出 %q 中解析子进程 PID", result.Output)
+			}
+			// 信号已在返回前发出，留出内核投递信号的时间
+			deadline := time.Now().Add(2 * time.Second)
+			for processAlive(pid) {
+				if time.Now().After(deadline) {
+					syscall.Kill(pid, syscall.SIGKILL)
+					t.Fatalf("超时后子进程 %d 仍在运行", pid)
+				}
+				time.Sleep(20 * time.Millisecond)
+			}
+		})
+	}
+}
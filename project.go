@@ -0,0 +1,51 @@
+package sandbox
+
+import (
+	"context"
+	"path/filepath"
+)
+
+// ExecuteProject 将 files（相对文件名到内容的映射）写入一个临时目录，
+// 以该目录为工作目录执行入口文件 entry，使入口文件可以导入同目录下的其他模块。
+// 文件名必须是不含 .. 的相对路径，执行结束后整个目录被删除。所有文件合计受 MaxCodeBytes 约束。
+// Go 项目只编译入口文件本身
+func (e *CodeExecutor) ExecuteProject(ctx context.Context, files map[string]string, entry string, language string) ExecutionResult {
+	// 写入项目文件之前登记执行，关闭后不再创建临时目录，Shutdown 也会等待文件写入完成。
+	// 代码开始运行之前的失败同样经过 execute，与 Execute 一样分配 ID、发出事件并计入指标
+	reject := func(result ExecutionResult) ExecutionResult {
+		return e.execute(ctx, "", language, ExecOptions{rejected: &result, admitted: true})
+	}
+	if !e.admit() {
+		return reject(e.fail(ErrorKindShuttingDown, MsgShuttingDown))
+	}
+	defer e.inflight.Done()
+
+	if _, ok := files[entry]; !ok {
+		return reject(e.fail(ErrorKindInvalidRequest, MsgEntryNotFound, entry))
+	}
+	var size int
+	for _, content := range files {
+		size += len(content)
+	}
+	if e.codeTooLarge(size) {
+		return reject(e.fail(ErrorKindCodeTooLarge, MsgCodeTooLarge, e.maxCodeBytes))
+	}
+
+	dir, err := e.tempFS.MkdirTemp(e.tempDir, "project-*")
+	if err != nil {
+		return reject(e.fail(ErrorKindInternal, MsgCreateTempDir, err))
+	}
+	defer e.removeTemp(dir)
+
+	for name, content := range files {
+		if err := e.writeWorkspaceFile(dir, name, []byte(content)); err != nil {
+			return reject(errorResultFrom(err))
+		}
+	}
+
+	return e.execute(ctx, "", language, ExecOptions{
+		WorkDir:    dir,
+		sourceFile: filepath.Join(dir, entry),
+		admitted:   true,
+	})
+}
@@ -0,0 +1,60 @@
+package sandbox
+
+import (
+	"context"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestExecuteProjectRejections(t *testing.T) {
+	tests := []struct {
+		name     string
+		files    map[string]string
+		entry    string
+		shutdown bool
+		wantKind ErrorKind
+	}{
+		{"missing entry", map[string]string{"main.py": "print(1)"}, "app.py", false, ErrorKindInvalidRequest},
+		{"code too large", map[string]string{"main.py": strings.Repeat("#", 40), "util.py": strings.Repeat("#", 40)}, "main.py", false, ErrorKindCodeTooLarge},
+		{"invalid file name", map[string]string{"main.py": "print(1)", "../escape.py": ""}, "main.py", false, ErrorKindInvalidRequest},
+		{"after shutdown", map[string]string{"main.py": "print(1)"}, "main.py", true, ErrorKindShuttingDown},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			var (
+				mu     sync.Mutex
+				events []ExecEvent
+			)
+			onEvent := func(event ExecEvent) {
+				mu.Lock()
+				defer mu.Unlock()
+				events = append(events, event)
+			}
+			e := newTestExecutor(t, "python3", Config{MaxCodeBytes: 64, TempDir: dir, OnEvent: onEvent})
+			if tt.shutdown {
+				e.Shutdown(context.Background())
+			}
+			result := e.ExecuteProject(context.Background(), tt.files, tt.entry, "python3")
+			if result.ErrorKind != tt.wantKind {
+				t.Fatalf("ErrorKind = %q，期望 %q（%s）", result.ErrorKind, tt.wantKind, result.Error)
+			}
+			if result.ID == "" || result.Language != "python3" {
+				t.Errorf("ID = %q，Language = %q，期望分配 ID 并记录语言", result.ID, result.Language)
+			}
+			if got := e.Metrics().Failures; got != 1 {
+				t.Errorf("Failures = %d，期望 1", got)
+			}
+			mu.Lock()
+			if len(events) != 1 || events[0].ID != result.ID || events[0].ErrorKind != tt.wantKind {
+				t.Errorf("事件 %+v，期望一个 ID 为 %q 的结束事件", events, result.ID)
+			}
+			mu.Unlock()
+			if entries, _ := os.ReadDir(dir); len(entries) > 0 {
+				t.Errorf("临时目录中残留 %d 项", len(entries))
+			}
+		})
+	}
+}
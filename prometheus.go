@@ -0,0 +1,113 @@
+package sandbox
+
+import "github.com/prometheus/client_golang/prometheus"
+
+// 结果标签 outcome 的取值，失败计数中不包括超时
+const (
+	outcomeSuccess = "success"
+	outcomeFailure = "failure"
+	outcomeTimeout = "timeout"
+)
+
+var (
+	executionsDesc = prometheus.NewDesc(
+		"sandbox_executions_total",
+		"Number of finished code executions by language and outcome.",
+		[]string{"language", "outcome"}, nil,
+	)
+	failuresDesc = prometheus.NewDesc(
+		"sandbox_execution_failures_total",
+		"Number of unsuccessful code executions, including timeouts.",
+		[]string{"language"}, nil,
+	)
+	timeoutsDesc = prometheus.NewDesc(
+		"sandbox_execution_timeouts_total",
+		"Number of code executions that exceeded their timeout.",
+		[]string{"language"}, nil,
+	)
+	durationDesc = prometheus.NewDesc(
+		"sandbox_execution_duration_seconds",
+		"Duration of code executions.",
+		[]string{"language"}, nil,
+	)
+	rejectedDesc = prometheus.NewDesc(
+		"sandbox_rejected_jobs_total",
+		"Number of jobs rejected by Submit because the queue was full and executions rejected because the worker pool was busy.",
+		nil, nil,
+	)
+	activeWorkersDesc = prometheus.NewDesc(
+		"sandbox_active_workers",
+		"Number of executions currently holding a worker.",
+		nil, nil,
+	)
+	maxWorkersDesc = prometheus.NewDesc(
+		"sandbox_max_workers",
+		"Maximum number of concurrent executions.",
+		nil, nil,
+	)
+	queueDepthDesc = prometheus.NewDesc(
+		"sandbox_queue_depth",
+		"Number of executions waiting for a worker.",
+		nil, nil,
+	)
+)
+
+// promCollector 在每次抓取时从执行器的计数器生成指标，自身不保存状态
+type promCollector struct {
+	e *CodeExecutor
+}
+
+// PrometheusCollector 返回导出执行器指标的 prometheus.Collector，由调用方注册到自己的 Registry。
+// 指标包括按语言和结果统计的执行数、失败与超时数、按语言的耗时直方图，以及活动工作者数和排队数；
+// 标签只有 language 与 outcome，基数受已注册语言数限制
+func (e *CodeExecutor) PrometheusCollector() prometheus.Collector {
+	return promCollector{e: e}
+}
+
+// Describe 实现 prometheus.Collector
+func (c promCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, desc := range []*prometheus.Desc{
+		executionsDesc, failuresDesc, timeoutsDesc, durationDesc,
+		rejectedDesc, activeWorkersDesc, maxWorkersDesc, queueDepthDesc,
+	} {
+		ch <- desc
+	}
+}
+
+// Collect 实现 prometheus.Collector
+func (c promCollector) Collect(ch chan<- prometheus.Metric) {
+	m := c.e.metrics
+	m.mu.RLock()
+	for language, counters := range m.byLanguage {
+		counters.collect(ch, language)
+	}
+	m.mu.RUnlock()
+
+	ch <- prometheus.MustNewConstMetric(rejectedDesc, prometheus.CounterValue, float64(m.rejected.Load()))
+	ch <- prometheus.MustNewConstMetric(activeWorkersDesc, prometheus.GaugeValue, float64(c.e.ActiveCount()))
+	ch <- prometheus.MustNewConstMetric(maxWorkersDesc, prometheus.GaugeValue, float64(c.e.MaxWorkers()))
+	ch <- prometheus.MustNewConstMetric(queueDepthDesc, prometheus.GaugeValue, float64(c.e.QueuedCount()))
+}
+
+// collect 把一个语言的计数写入 ch
+func (c *counters) collect(ch chan<- prometheus.Metric, language string) {
+	snapshot := c.snapshot()
+	// 超时单独计为 timeout，不重复计入 failure
+	failures := snapshot.Failures - min(snapshot.Timeouts, snapshot.Failures)
+	ch <- prometheus.MustNewConstMetric(executionsDesc, prometheus.CounterValue, float64(snapshot.Successes), language, outcomeSuccess)
+	ch <- prometheus.MustNewConstMetric(executionsDesc, prometheus.CounterValue, float64(failures), language, outcomeFailure)
+	ch <- prometheus.MustNewConstMetric(executionsDesc, prometheus.CounterValue, float64(snapshot.Timeouts), language, outcomeTimeout)
+	ch <- prometheus.MustNewConstMetric(failuresDesc, prometheus.CounterValue, float64(snapshot.Failures), language)
+	ch <- prometheus.MustNewConstMetric(timeoutsDesc, prometheus.CounterValue, float64(snapshot.Timeouts), language)
+
+	buckets := make(map[float64]uint64, len(durationBuckets))
+	var cumulative uint64
+	for i, bound := range durationBuckets {
+		cumulative += c.durations[i].Load()
+		buckets[bound] = cumulative
+	}
+	// 各计数分别读取，抓取期间有执行结束时总数可能落后于桶计数
+	count := max(snapshot.Executions, cumulative)
+	sum := float64(c.durationMs.Load()) / 1000
+	ch <- prometheus.MustNewConstHistogram(durationDesc, count, sum, buckets, language)
+}
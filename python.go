@@ -0,0 +1,75 @@
+package sandbox
+
+import (
+	"fmt"
+	"os/exec"
+	"sort"
+	"sync"
+)
+
+// pythonVersionCandidates 是启动时探测的 Python 解释器名称
+var pythonVersionCandidates = func() []string {
+	var names []string
+	for minor := 6; minor <= 15; minor++ {
+		names = append(names, fmt.Sprintf("python3.%d", minor))
+	}
+	return names
+}()
+
+// discoverPythonVersions 在 PATH 中查找各个版本的 Python 解释器，并确认其能够运行，
+// 返回解释器名称到绝对路径及到版本信息的映射。探测并发进行，结果在执行器的生命周期内复用
+func discoverPythonVersions() (paths map[string]string, versions map[string]string) {
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	paths = make(map[string]string)
+	versions = make(map[string]string)
+	for _, name := range pythonVersionCandidates {
+		path, err := exec.LookPath(name)
+		if err != nil {
+			continue
+		}
+		wg.Add(1)
+		go func(name, path string) {
+			defer wg.Done()
+			// pyenv 等工具会为未安装的版本留下无法运行的 shim
+			if version, ok := runtimeVersion(path, "--version"); ok {
+				mu.Lock()
+				paths[name] = path
+				versions[name] = version
+				mu.Unlock()
+			}
+		}(name, path)
+	}
+	wg.Wait()
+	return paths, versions
+}
+
+// PythonVersions 返回启动时（或最近一次 RefreshRuntimes 时）发现的可用 Python 解释器名称（如 "python3.11"），
+// 可作为 ExecOptions.PythonVersion 的取值
+func (e *CodeExecutor) PythonVersions() []string {
+	versions := e.currentRuntimes().pythonVersions
+	names := make([]string, 0, len(versions))
+	for name := range versions {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		return pythonMinor(names[i]) < pythonMinor(names[j])
+	})
+	return names
+}
+
+// pythonMinor 返回 "python3.N" 中的 N，用于按版本而不是按字典序排序
+func pythonMinor(name string) int {
+	var minor int
+	fmt.Sscanf(name, "python3.%d", &minor)
+	return minor
+}
+
+// pythonInterpreter 返回本次执行使用的 Python 解释器：version 是已发现的版本时使用该版本，
+// 否则回退到 Config.PythonPath
+func (e *CodeExecutor) pythonInterpreter(version string) string {
+	if path, ok := e.currentRuntimes().pythonVersions[version]; ok {
+		return path
+	}
+	return e.pythonPath
+}
@@ -0,0 +1,77 @@
+package sandbox
+
+import (
+	"sync"
+	"time"
+)
+
+// RateLimiter 决定某个客户端此刻能否开始一次执行，实现必须可被并发调用。
+// 可替换为基于 Redis 等的分布式实现
+type RateLimiter interface {
+	Allow(clientID string) bool
+}
+
+// TokenBucketLimiter 是按客户端ID划分的内存令牌桶：每个客户端每 interval 最多执行 rate 次，
+// 令牌按时间平滑补充，空闲的客户端最多攒下 rate 个令牌
+type TokenBucketLimiter struct {
+	rate     float64
+	interval time.Duration
+
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// NewTokenBucketLimiter 创建每个客户端每 interval 最多允许 rate 次执行的限流器
+func NewTokenBucketLimiter(rate int, interval time.Duration) *TokenBucketLimiter {
+	return &TokenBucketLimiter{
+		rate:      float64(rate),
+		interval:  interval,
+		buckets:   make(map[string]*tokenBucket),
+		lastSweep: time.Now(),
+	}
+}
+
+// Allow 消耗 clientID 的一个令牌，令牌不足时返回 false
+func (l *TokenBucketLimiter) Allow(clientID string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.sweep(now)
+
+	bucket, ok := l.buckets[clientID]
+	if !ok {
+		bucket = &tokenBucket{tokens: l.rate, last: now}
+		l.buckets[clientID] = bucket
+	}
+	bucket.tokens += now.Sub(bucket.last).Seconds() / l.interval.Seconds() * l.rate
+	if bucket.tokens > l.rate {
+		bucket.tokens = l.rate
+	}
+	bucket.last = now
+
+	if bucket.tokens < 1 {
+		return false
+	}
+	bucket.tokens--
+	return true
+}
+
+// sweep 每隔 interval 删除已经补满的令牌桶，避免客户端ID无限累积，调用方须持有 l.mu
+func (l *TokenBucketLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < l.interval {
+		return
+	}
+	l.lastSweep = now
+	for clientID, bucket := range l.buckets {
+		if now.Sub(bucket.last) >= l.interval {
+			delete(l.buckets, clientID)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package sandbox
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// sourceExtensions 是能直接执行源文件的内置语言及其文件扩展名，
+// ExecuteReader 把这些语言的代码边读边写入文件，其余语言先读入内存
+var sourceExtensions = map[string]string{
+	"python3":    ".py",
+	"nodejs":     ".js",
+	"go":         ".go",
+	"c":          ".c",
+	"rust":       ".rs",
+	"ruby":       ".rb",
+	"bash":       ".sh",
+	"typescript": ".ts",
+	"deno":       ".ts",
+}
+
+// ExecuteReader 从 r 读取 language 代码并执行，适合较大的源代码：内置的脚本与编译型语言的代码
+// 边读边写入临时目录中的源文件，不在内存中保留完整副本，并以该目录为工作目录执行；
+// SQL、WebAssembly、Java（源文件须以代码中的类名命名）、配置了 PreludeCode 或 PostludeCode 的语言与自定义 Runner 的代码仍会先读入内存。读取超过 MaxCodeBytes 时立即停止并以
+// ErrCodeTooLarge 失败，读取出错时以 ErrorKindInvalidRequest 失败。这种执行不参与结果缓存
+func (e *CodeExecutor) ExecuteReader(ctx context.Context, r io.Reader, language string) ExecutionResult {
+	// 代码开始运行之前的失败同样经过 execute，与 Execute 一样分配 ID、发出事件并计入指标
+	reject := func(result ExecutionResult) ExecutionResult {
+		return e.execute(ctx, "", language, ExecOptions{rejected: &result})
+	}
+	// 不能执行时无需读取代码
+	if err := e.unavailableError(language); err != nil {
+		return reject(errorResultFrom(err))
+	}
+	// 多读一个字节即可判断是否超出上限
+	if e.maxCodeBytes > 0 {
+		r = io.LimitReader(r, int64(e.maxCodeBytes)+1)
+	}
+
+	ext, ok := sourceExtensions[language]
+	wrapped := e.preludes[language] != "" || e.postludes[language] != ""
+	if _, builtin := e.runner(language).(*builtinRunner); !ok || !builtin || wrapped {
+		var code strings.Builder
+		if _, err := io.Copy(&code, r); err != nil {
+			return reject(e.fail(ErrorKindInvalidRequest, MsgReadCode, err))
+		}
+		if e.codeTooLarge(code.Len()) {
+			return reject(e.fail(ErrorKindCodeTooLarge, MsgCodeTooLarge, e.maxCodeBytes))
+		}
+		return e.execute(ctx, code.String(), language, ExecOptions{})
+	}
+
+	dir, err := e.tempFS.MkdirTemp(e.tempDir, "reader-*")
+	if err != nil {
+		return reject(e.fail(ErrorKindInternal, MsgCreateTempDir, err))
+	}
+	defer e.removeTemp(dir)
+
+	src := filepath.Join(dir, "main"+ext)
+	file, err := e.tempFS.OpenFile(src, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return reject(e.fail(ErrorKindInternal, MsgWriteCode, err))
+	}
+	// readErr 记录来自 r 的错误，io.Copy 返回的其他错误来自写入临时文件
+	var readErr error
+	n, err := io.Copy(file, readerFunc(func(p []byte) (int, error) {
+		// 读取大的输入期间调用方可能已经放弃
+		if err := ctx.Err(); err != nil {
+			return 0, err
+		}
+		n, err := r.Read(p)
+		if err != nil && err != io.EOF {
+			readErr = err
+		}
+		return n, err
+	}))
+	if closeErr := file.Close(); err == nil && closeErr != nil {
+		return reject(e.fail(ErrorKindInternal, MsgWriteCode, closeErr))
+	}
+	if err != nil {
+		if ctx.Err() != nil {
+			return reject(e.canceledResult(ctx, e.timeoutFor(language, 0), 0))
+		}
+		if readErr == nil {
+			return reject(e.fail(ErrorKindInternal, MsgWriteCode, err))
+		}
+		return reject(e.fail(ErrorKindInvalidRequest, MsgReadCode, err))
+	}
+	if e.codeTooLarge(int(n)) {
+		return reject(e.fail(ErrorKindCodeTooLarge, MsgCodeTooLarge, e.maxCodeBytes))
+	}
+
+	return e.execute(ctx, "", language, ExecOptions{
+		WorkDir:    dir,
+		sourceFile: src,
+	})
+}
+
+// readerFunc 把函数适配为 io.Reader
+type readerFunc func(p []byte) (int, error)
+
+func (f readerFunc) Read(p []byte) (int, error) {
+	return f(p)
+}
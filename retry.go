@@ -0,0 +1,75 @@
+package sandbox
+
+import (
+	"context"
+	"errors"
+	"syscall"
+	"time"
+)
+
+// RetryPolicy 描述执行因沙箱自身的临时故障失败时的重试策略，例如负载高峰时创建进程遇到
+// EAGAIN、ENOMEM，或临时文件遇到 EBUSY。用户代码的失败、超时、取消及其余内部错误都不会重试
+type RetryPolicy struct {
+	MaxAttempts int           // 包括首次在内的最大执行次数，小于等于 1 时不重试
+	Backoff     time.Duration // 第一次重试前的等待时间，此后每次翻倍
+	MaxBackoff  time.Duration // 单次等待时间的上限，为 0 时不限制
+}
+
+// transientErrnos 是被视为临时故障的系统调用错误，稍后重试通常能够成功
+var transientErrnos = []syscall.Errno{
+	syscall.EAGAIN,
+	syscall.ENOMEM,
+	syscall.EBUSY,
+	syscall.ETXTBSY,
+	syscall.EMFILE,
+	syscall.ENFILE,
+	syscall.EINTR,
+}
+
+// isTransient 判断 err 是否为临时的系统资源故障
+func isTransient(err error) bool {
+	for _, errno := range transientErrnos {
+		if errors.Is(err, errno) {
+			return true
+		}
+	}
+	return false
+}
+
+// anyTransient 判断消息参数中是否有临时故障的错误，用于 fail 与 sandboxError 标记结果
+func anyTransient(args []any) bool {
+	for _, arg := range args {
+		if err, ok := arg.(error); ok && isTransient(err) {
+			return true
+		}
+	}
+	return false
+}
+
+// shouldRetry 判断第 attempt 次执行的结果 result 是否应当重试
+func (p *RetryPolicy) shouldRetry(result ExecutionResult, attempt int) bool {
+	return p != nil && attempt < p.MaxAttempts && result.ErrorKind == ErrorKindInternal && result.transient
+}
+
+// wait 在第 attempt 次重试前等待退避时间，ctx 先结束时返回 false
+func (p *RetryPolicy) wait(ctx context.Context, attempt int) bool {
+	delay := p.Backoff
+	for i := 1; i < attempt; i++ {
+		delay *= 2
+	}
+	if p.MaxBackoff > 0 {
+		delay = min(delay, p.MaxBackoff)
+	}
+	if delay <= 0 {
+		return ctx.Err() == nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
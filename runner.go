@@ -0,0 +1,86 @@
+package sandbox
+
+import (
+	"context"
+	"os/exec"
+)
+
+// Runner 负责执行一种语言的代码，实现必须可被并发调用。
+// 超时与取消由执行器通过 ctx 控制，Run 应在 ctx 结束后尽快返回
+type Runner interface {
+	Run(ctx context.Context, code string, opts ExecOptions) ExecutionResult
+	// Available 报告该语言的运行时在当前主机上是否可用
+	Available() bool
+}
+
+// builtinRunner 是内置语言的 Runner，name 用于运行时不可用时的提示
+type builtinRunner struct {
+	name      string
+	run       func(ctx context.Context, code string, opts ExecOptions) ExecutionResult
+	available func() bool
+}
+
+func (r *builtinRunner) Run(ctx context.Context, code string, opts ExecOptions) ExecutionResult {
+	return r.run(ctx, code, opts)
+}
+
+func (r *builtinRunner) Available() bool {
+	return r.available()
+}
+
+// builtinRunners 返回内置语言的 Runner，键与 supportedLanguages 一致
+func (e *CodeExecutor) builtinRunners() map[string]Runner {
+	return map[string]Runner{
+		"python3": &builtinRunner{"Python", e.runPythonCode, func() bool { return e.currentRuntimes().pythonAvailable }},
+		"nodejs":  &builtinRunner{"Node.js", e.runNodeJSCode, func() bool { return e.currentRuntimes().nodejsAvailable }},
+		"go":      &builtinRunner{"Go", e.runGoCode, func() bool { return e.currentRuntimes().goAvailable }},
+		"c":       &builtinRunner{"C", e.runCCode, func() bool { return e.currentRuntimes().cCompiler != "" }},
+		"rust":    &builtinRunner{"Rust", e.runRustCode, func() bool { return e.currentRuntimes().rustAvailable }},
+		"java":    &builtinRunner{"Java", e.runJavaCode, func() bool { return e.currentRuntimes().javaAvailable }},
+		"ruby":    &builtinRunner{"Ruby", e.runRubyCode, func() bool { return e.currentRuntimes().rubyAvailable }},
+		"bash":    &builtinRunner{"Bash", e.runBashCode, func() bool { return e.currentRuntimes().bashPath != "" }},
+		"typescript": &builtinRunner{"TypeScript", func(ctx context.Context, code string, opts ExecOptions) ExecutionResult {
+			if e.currentRuntimes().tsNodeAvailable {
+				return e.runTypeScriptCode(ctx, code, opts)
+			}
+			return e.runTypeScriptWithTsc(ctx, code, opts)
+		}, func() bool { return e.currentRuntimes().tsAvailable }},
+		"deno": &builtinRunner{"Deno", e.runDenoCode, func() bool { return e.currentRuntimes().denoAvailable }},
+		"sql":  &builtinRunner{"SQLite", e.runSQLCode, func() bool { return e.currentRuntimes().sqliteAvailable }},
+		"wasm": &builtinRunner{"WebAssembly", e.runWasmCode, func() bool { return e.currentRuntimes().wasmAvailable }},
+	}
+}
+
+// RegisterRunner 注册 name 对应语言的 Runner，之后即可通过 Execute 等方法执行该语言的代码。
+// name 已存在时替换原有的 Runner，包括内置语言。可与执行并发调用
+func (e *CodeExecutor) RegisterRunner(name string, r Runner) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if _, exists := e.runners[name]; !exists {
+		e.languages = append(e.languages, name)
+	}
+	e.runners[name] = r
+	e.metrics.addLanguage(name)
+}
+
+// runner 返回 language 对应的 Runner，未注册时返回 nil
+func (e *CodeExecutor) runner(language string) Runner {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return e.runners[language]
+}
+
+// RunCommand 以 context.Background() 调用 RunCommandContext，运行阶段的 span 不会关联到本次执行
+func (e *CodeExecutor) RunCommand(cmd *exec.Cmd, opts ExecOptions) ExecutionResult {
+	return e.RunCommandContext(context.Background(), cmd, opts)
+}
+
+// RunCommandContext 按执行器的配置运行 cmd：设置标准输入、环境变量和工作目录，
+// 施加资源限制、网络隔离与输出上限。供自定义 Runner 启动子进程使用，ctx 应为 Run 收到的 ctx，
+// 启动与等待阶段的 span 记录在本次执行的 span 之下；cmd 应由 exec.CommandContext 以同一个 ctx 创建，
+// 以便超时与取消时被终止
+func (e *CodeExecutor) RunCommandContext(ctx context.Context, cmd *exec.Cmd, opts ExecOptions) ExecutionResult {
+	return e.runUserCommand(ctx, cmd, opts)
+}
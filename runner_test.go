@@ -0,0 +1,109 @@
+package sandbox
+
+import (
+	"context"
+	"os/exec"
+	"sync"
+	"testing"
+
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/embedded"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// recordingProvider 是记录每个 span 名称及其父 span 的 TracerProvider
+type recordingProvider struct {
+	embedded.TracerProvider
+	mu      sync.Mutex
+	next    uint64
+	parents map[string]trace.SpanID // span 名称到父 span ID
+	ids     map[string]trace.SpanID // span 名称到 span ID
+}
+
+func newRecordingProvider() *recordingProvider {
+	return &recordingProvider{parents: map[string]trace.SpanID{}, ids: map[string]trace.SpanID{}}
+}
+
+func (p *recordingProvider) Tracer(string, ...trace.TracerOption) trace.Tracer {
+	return recordingTracer{provider: p}
+}
+
+type recordingTracer struct {
+	embedded.Tracer
+	provider *recordingProvider
+}
+
+func (t recordingTracer) Start(ctx context.Context, name string, _ ...trace.SpanStartOption) (context.Context, trace.Span) {
+	p := t.provider
+	p.mu.Lock()
+	p.next++
+	var id trace.SpanID
+	id[7] = byte(p.next)
+	p.parents[name] = trace.SpanContextFromContext(ctx).SpanID()
+	p.ids[name] = id
+	p.mu.Unlock()
+	span := recordingSpan{provider: p, spanContext: trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: trace.TraceID{1},
+		SpanID:  id,
+	})}
+	return trace.ContextWithSpan(ctx, span), span
+}
+
+type recordingSpan struct {
+	noop.Span
+	provider    *recordingProvider
+	spanContext trace.SpanContext
+}
+
+func (s recordingSpan) SpanContext() trace.SpanContext       { return s.spanContext }
+func (s recordingSpan) TracerProvider() trace.TracerProvider { return s.provider }
+
+// commandRunner 是通过 RunCommandContext 或 RunCommand 运行 echo 的自定义 Runner
+type commandRunner struct {
+	e          *CodeExecutor
+	useContext bool
+}
+
+func (r commandRunner) Run(ctx context.Context, code string, opts ExecOptions) ExecutionResult {
+	cmd := exec.CommandContext(ctx, "echo", code)
+	if r.useContext {
+		return r.e.RunCommandContext(ctx, cmd, opts)
+	}
+	return r.e.RunCommand(cmd, opts)
+}
+
+func (commandRunner) Available() bool { return true }
+
+func TestRunCommandContextSpans(t *testing.T) {
+	if _, err := exec.LookPath("echo"); err != nil {
+		t.Skip("需要 echo")
+	}
+	tests := []struct {
+		name       string
+		useContext bool
+		wantParent bool
+	}{
+		{"RunCommandContext", true, true},
+		{"RunCommand", false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := newRecordingProvider()
+			e := NewCodeExecutorWithConfig(Config{TracerProvider: provider})
+			t.Cleanup(func() { e.Shutdown(context.Background()) })
+			e.RegisterRunner("echo", commandRunner{e: e, useContext: tt.useContext})
+
+			result := e.Execute("hello", "echo")
+			if !result.Success || result.Output != "hello\n" {
+				t.Fatalf("执行失败: %s", result.Error)
+			}
+			provider.mu.Lock()
+			defer provider.mu.Unlock()
+			execID := provider.ids["sandbox.execute"]
+			parent, ok := provider.parents["sandbox.spawn"]
+			if got := ok && parent == execID; got != tt.wantParent {
+				t.Errorf("sandbox.spawn 位于执行 span 之下 = %v，期望 %v", got, tt.wantParent)
+			}
+		})
+	}
+}
@@ -0,0 +1,168 @@
+// Package sandboxgrpc 通过 gRPC 暴露 sandbox.CodeExecutor，协议定义见 sandbox.proto。
+// 独立成包使核心的 sandbox 包不依赖 gRPC 与 protobuf
+package sandboxgrpc
+
+//go:generate buf generate
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	sandbox "github.com/open-mcp-app/mcp-server-sandbox"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Server 是由 CodeExecutor 实现的 SandboxServer
+type Server struct {
+	UnimplementedSandboxServer
+	e *sandbox.CodeExecutor
+}
+
+// NewServer 创建使用 e 执行代码的 Server
+func NewServer(e *sandbox.CodeExecutor) *Server {
+	return &Server{e: e}
+}
+
+// Register 把使用 e 执行代码的 Sandbox 服务注册到 s
+func Register(s grpc.ServiceRegistrar, e *sandbox.CodeExecutor) {
+	RegisterSandboxServer(s, NewServer(e))
+}
+
+// Execute 执行代码并返回结果。代码本身的失败（编译错误、运行时错误、触发资源限制等）返回 OK，
+// 由结果中的 error_kind 区分；请求无法执行或超时时返回对应的状态码，结果附在状态的 details 中
+func (s *Server) Execute(ctx context.Context, req *ExecuteRequest) (*ExecuteResponse, error) {
+	result := s.e.ExecuteContextWithOptions(ctx, req.GetCode(), req.GetLanguage(), execOptions(req))
+	pb := resultToProto(result)
+	if err := resultStatus(result, pb); err != nil {
+		return nil, err
+	}
+	return &ExecuteResponse{Result: pb}, nil
+}
+
+// ExecuteStream 执行代码并按行实时发送输出，最后一条消息携带执行结果。
+// 状态码的规则与 Execute 相同，非 OK 时仍会先发送结果消息
+func (s *Server) ExecuteStream(req *ExecuteRequest, stream grpc.ServerStreamingServer[ExecuteStreamResponse]) error {
+	chunks, err := s.e.ExecuteStreamWithOptions(stream.Context(), req.GetCode(), req.GetLanguage(), execOptions(req))
+	if err != nil {
+		return errorStatus(err)
+	}
+
+	for chunk := range chunks {
+		if chunk.Result != nil {
+			pb := resultToProto(*chunk.Result)
+			if err := stream.Send(&ExecuteStreamResponse{Event: &ExecuteStreamResponse_Result{Result: pb}}); err != nil {
+				return err
+			}
+			return resultStatus(*chunk.Result, pb)
+		}
+		msg := &OutputChunk{Stream: streamToProto(chunk.Stream), Data: chunk.Data}
+		if err := stream.Send(&ExecuteStreamResponse{Event: &ExecuteStreamResponse_Chunk{Chunk: msg}}); err != nil {
+			// 客户端已断开，stream.Context() 随之取消执行；继续读空通道让执行协程退出
+			for range chunks {
+			}
+			return err
+		}
+	}
+	// ctx 被取消时通道可能在发送结果前关闭
+	return status.FromContextError(stream.Context().Err()).Err()
+}
+
+// execOptions 把请求转换为单次执行选项
+func execOptions(req *ExecuteRequest) sandbox.ExecOptions {
+	return sandbox.ExecOptions{
+		Stdin:   req.GetStdin(),
+		Timeout: req.GetTimeout().AsDuration(),
+		Args:    req.GetArgs(),
+		Env:     req.GetEnv(),
+	}
+}
+
+// statusCode 返回错误分类对应的 gRPC 状态码，代码本身的失败返回 codes.OK
+func statusCode(kind sandbox.ErrorKind) codes.Code {
+	switch kind {
+	case sandbox.ErrorKindInvalidRequest, sandbox.ErrorKindLanguageUnsupported, sandbox.ErrorKindCodeTooLarge:
+		return codes.InvalidArgument
+	case sandbox.ErrorKindTimeout:
+		return codes.DeadlineExceeded
+	case sandbox.ErrorKindCanceled:
+		return codes.Canceled
+	case sandbox.ErrorKindBusy, sandbox.ErrorKindRateLimited, sandbox.ErrorKindQueueFull:
+		return codes.ResourceExhausted
+	case sandbox.ErrorKindRuntimeUnavailable, sandbox.ErrorKindShuttingDown:
+		return codes.Unavailable
+	case sandbox.ErrorKindInternal:
+		return codes.Internal
+	}
+	return codes.OK
+}
+
+// resultStatus 返回执行结果对应的状态错误，结果为 OK 时返回 nil。pb 作为状态的 details 返回给客户端
+func resultStatus(result sandbox.ExecutionResult, pb *ExecutionResult) error {
+	code := statusCode(result.ErrorKind)
+	if code == codes.OK {
+		return nil
+	}
+	st := status.New(code, result.Error)
+	if detailed, err := st.WithDetails(pb); err == nil {
+		st = detailed
+	}
+	return st.Err()
+}
+
+// errorStatus 把 ExecuteStreamWithOptions 返回的错误转换为状态错误
+func errorStatus(err error) error {
+	var sandboxErr *sandbox.SandboxError
+	if errors.As(err, &sandboxErr) {
+		return status.Error(statusCode(sandboxErr.Kind), sandboxErr.Message)
+	}
+	return status.Error(codes.Internal, err.Error())
+}
+
+// resultToProto 把执行结果转换为协议消息
+func resultToProto(r sandbox.ExecutionResult) *ExecutionResult {
+	return &ExecutionResult{
+		Id:             r.ID,
+		Success:        r.Success,
+		Output:         r.Output,
+		Error:          r.Error,
+		Stderr:         r.Stderr,
+		ErrorKind:      string(r.ErrorKind),
+		ExitCode:       int32(r.ExitCode),
+		Signal:         r.Signal,
+		ErrorPhase:     string(r.ErrorPhase),
+		DurationMs:     r.DurationMs,
+		Truncated:      r.Truncated,
+		InvalidUtf8:    r.InvalidUTF8,
+		MaxRssBytes:    r.MaxRSSBytes,
+		CpuTimeMs:      r.CPUTimeMs,
+		Language:       r.Language,
+		RuntimeVersion: r.RuntimeVersion,
+		Attempts:       int32(r.Attempts),
+		StartedAt:      timestampToProto(r.StartedAt),
+		FinishedAt:     timestampToProto(r.FinishedAt),
+		QueueWaitMs:    r.QueueWaitMs,
+	}
+}
+
+// timestampToProto 转换时刻，零值转换为 nil，使字段保持未设置
+func timestampToProto(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(t)
+}
+
+// streamToProto 把 sandbox.StreamStdout 等流标识转换为协议枚举
+func streamToProto(stream string) Stream {
+	switch stream {
+	case sandbox.StreamStdout:
+		return Stream_STREAM_STDOUT
+	case sandbox.StreamStderr:
+		return Stream_STREAM_STDERR
+	}
+	return Stream_STREAM_UNSPECIFIED
+}
@@ -0,0 +1,233 @@
+package sandboxmcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"sync"
+)
+
+// protocolVersions 是 Server 支持的 MCP 协议版本，第一个为首选版本
+var protocolVersions = []string{"2025-06-18", "2025-03-26", "2024-11-05"}
+
+// JSON-RPC 错误码
+const (
+	codeParseError     = -32700
+	codeInvalidRequest = -32600
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+)
+
+// Server 是基于标准输入输出（每行一条 JSON-RPC 消息）的最小 MCP 服务端，只提供工具能力。
+// 工具调用并发处理，客户端发送 notifications/cancelled 时取消对应调用的 ctx
+type Server struct {
+	name    string
+	version string
+
+	mu       sync.Mutex
+	tools    []Tool
+	handlers map[string]ToolHandler
+}
+
+// NewServer 创建在 initialize 中以 name 与 version 标识自己的 Server
+func NewServer(name string, version string) *Server {
+	return &Server{name: name, version: version, handlers: make(map[string]ToolHandler)}
+}
+
+// AddTool 注册一个工具，同名工具会被替换，实现 ToolRegistrar
+func (s *Server) AddTool(tool Tool, handler ToolHandler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.handlers[tool.Name]; exists {
+		for i := range s.tools {
+			if s.tools[i].Name == tool.Name {
+				s.tools[i] = tool
+			}
+		}
+	} else {
+		s.tools = append(s.tools, tool)
+	}
+	s.handlers[tool.Name] = handler
+}
+
+// ServeStdio 在标准输入输出上运行 Serve
+func (s *Server) ServeStdio(ctx context.Context) error {
+	return s.Serve(ctx, os.Stdin, os.Stdout)
+}
+
+// rpcMessage 是收到的 JSON-RPC 请求或通知，通知没有 ID
+type rpcMessage struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+// rpcResponse 是发出的 JSON-RPC 响应
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// Serve 从 r 逐行读取 JSON-RPC 消息并把响应写入 w，直到 r 结束或 ctx 被取消。
+// r 在单独的 goroutine 中读取，ctx 被取消时不等下一行到达，立即取消进行中的工具调用并在它们返回后返回 ctx.Err()；
+// 阻塞中的读取在 r 的下一次读取返回后才结束。r 正常结束时等待进行中的工具调用完成并返回 nil
+func (s *Server) Serve(ctx context.Context, r io.Reader, w io.Writer) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var writeMu sync.Mutex
+	send := func(resp rpcResponse) {
+		resp.JSONRPC = "2.0"
+		data, _ := json.Marshal(resp)
+		writeMu.Lock()
+		defer writeMu.Unlock()
+		w.Write(append(data, '\n'))
+	}
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	var callsMu sync.Mutex
+	calls := make(map[string]context.CancelFunc)
+
+	type readResult struct {
+		line []byte
+		err  error
+	}
+	lines := make(chan readResult)
+	go func() {
+		reader := bufio.NewReader(r)
+		for {
+			line, err := reader.ReadBytes('\n')
+			select {
+			case lines <- readResult{line, err}:
+			case <-ctx.Done():
+				return
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	for {
+		var next readResult
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case next = <-lines:
+		}
+		line, err := next.line, next.err
+		if len(line) > 0 {
+			var msg rpcMessage
+			if jsonErr := json.Unmarshal(line, &msg); jsonErr != nil {
+				send(rpcResponse{ID: json.RawMessage("null"), Error: &rpcError{codeParseError, jsonErr.Error()}})
+			} else if msg.Method == "tools/call" && len(msg.ID) > 0 {
+				callCtx, callCancel := context.WithCancel(ctx)
+				callsMu.Lock()
+				calls[string(msg.ID)] = callCancel
+				callsMu.Unlock()
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					defer func() {
+						callsMu.Lock()
+						delete(calls, string(msg.ID))
+						callsMu.Unlock()
+						callCancel()
+					}()
+					send(s.callTool(callCtx, msg))
+				}()
+			} else if msg.Method == "notifications/cancelled" {
+				var params struct {
+					RequestID json.RawMessage `json:"requestId"`
+				}
+				if json.Unmarshal(msg.Params, &params) == nil {
+					callsMu.Lock()
+					if callCancel, ok := calls[string(params.RequestID)]; ok {
+						callCancel()
+					}
+					callsMu.Unlock()
+				}
+			} else if len(msg.ID) > 0 {
+				send(s.handle(msg))
+			}
+			// 其余没有 ID 的通知（如 notifications/initialized）无需响应
+		}
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// handle 处理 tools/call 以外的请求
+func (s *Server) handle(msg rpcMessage) rpcResponse {
+	resp := rpcResponse{ID: msg.ID}
+	switch msg.Method {
+	case "initialize":
+		var params struct {
+			ProtocolVersion string `json:"protocolVersion"`
+		}
+		json.Unmarshal(msg.Params, &params)
+		version := protocolVersions[0]
+		for _, supported := range protocolVersions {
+			if params.ProtocolVersion == supported {
+				version = supported
+			}
+		}
+		resp.Result = map[string]any{
+			"protocolVersion": version,
+			"capabilities":    map[string]any{"tools": map[string]any{}},
+			"serverInfo":      map[string]any{"name": s.name, "version": s.version},
+		}
+	case "ping":
+		resp.Result = struct{}{}
+	case "tools/list":
+		s.mu.Lock()
+		tools := append([]Tool{}, s.tools...)
+		s.mu.Unlock()
+		resp.Result = map[string]any{"tools": tools}
+	default:
+		if msg.Method == "" {
+			resp.Error = &rpcError{codeInvalidRequest, "missing method"}
+		} else {
+			resp.Error = &rpcError{codeMethodNotFound, "method not found: " + msg.Method}
+		}
+	}
+	return resp
+}
+
+// callTool 处理 tools/call 请求
+func (s *Server) callTool(ctx context.Context, msg rpcMessage) rpcResponse {
+	var params struct {
+		Name      string          `json:"name"`
+		Arguments json.RawMessage `json:"arguments"`
+	}
+	if err := json.Unmarshal(msg.Params, &params); err != nil {
+		return rpcResponse{ID: msg.ID, Error: &rpcError{codeInvalidParams, err.Error()}}
+	}
+	s.mu.Lock()
+	handler, ok := s.handlers[params.Name]
+	s.mu.Unlock()
+	if !ok {
+		return rpcResponse{ID: msg.ID, Error: &rpcError{codeInvalidParams, "unknown tool: " + params.Name}}
+	}
+	if len(params.Arguments) == 0 {
+		params.Arguments = json.RawMessage("{}")
+	}
+	return rpcResponse{ID: msg.ID, Result: handler(ctx, params.Arguments)}
+}
@@ -0,0 +1,48 @@
+package sandboxmcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestServeReturnsOnCancel(t *testing.T) {
+	started := make(chan struct{})
+	canceled := make(chan struct{})
+	s := NewServer("test", "0")
+	s.AddTool(Tool{Name: "block"}, func(ctx context.Context, _ json.RawMessage) CallToolResult {
+		close(started)
+		<-ctx.Done()
+		close(canceled)
+		return CallToolResult{}
+	})
+
+	// 管道的写入端一直不关闭，Serve 阻塞在读取下一行上
+	r, w := io.Pipe()
+	defer w.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- s.Serve(ctx, r, io.Discard) }()
+
+	if _, err := io.WriteString(w, `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"block"}}`+"\n"); err != nil {
+		t.Fatal(err)
+	}
+	<-started
+	cancel()
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Serve 返回 %v，期望 context.Canceled", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("ctx 取消后 Serve 没有返回")
+	}
+	select {
+	case <-canceled:
+	default:
+		t.Error("Serve 返回时进行中的工具调用没有被取消")
+	}
+}
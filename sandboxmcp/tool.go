@@ -0,0 +1,156 @@
+// Package sandboxmcp 把 sandbox.CodeExecutor 暴露为 MCP（Model Context Protocol）工具。
+// NewTool 返回与具体 SDK 无关的工具定义与处理函数，可通过 ToolRegistrar 接入任意 MCP 服务端实现；
+// Server 是一个基于标准输入输出的最小 MCP 服务端，示例见 cmd/sandbox-mcp
+package sandboxmcp
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	sandbox "github.com/open-mcp-app/mcp-server-sandbox"
+)
+
+// ToolName 是执行代码的工具名
+const ToolName = "execute_code"
+
+// Tool 是 MCP tools/list 中的一个工具定义
+type Tool struct {
+	Name         string          `json:"name"`
+	Description  string          `json:"description,omitempty"`
+	InputSchema  json.RawMessage `json:"inputSchema"`
+	OutputSchema json.RawMessage `json:"outputSchema,omitempty"`
+}
+
+// Content 是工具结果中的一段文本内容
+type Content struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// CallToolResult 是 MCP tools/call 的结果
+type CallToolResult struct {
+	Content           []Content `json:"content"`
+	StructuredContent any       `json:"structuredContent,omitempty"`
+	IsError           bool      `json:"isError,omitempty"`
+}
+
+// ToolHandler 处理一次工具调用，arguments 是调用参数的 JSON 对象
+type ToolHandler func(ctx context.Context, arguments json.RawMessage) CallToolResult
+
+// ToolRegistrar 是能够注册工具的 MCP 服务端，Server 实现了它，其他 SDK 可以用几行适配代码实现
+type ToolRegistrar interface {
+	AddTool(tool Tool, handler ToolHandler)
+}
+
+// Register 把执行代码的工具注册到 r
+func Register(r ToolRegistrar, e *sandbox.CodeExecutor) {
+	r.AddTool(NewTool(e))
+}
+
+// toolArguments 是工具的调用参数
+type toolArguments struct {
+	Code     string  `json:"code"`
+	Language string  `json:"language"`
+	Stdin    string  `json:"stdin"`
+	Timeout  float64 `json:"timeout"`
+}
+
+// NewTool 返回执行代码的工具定义及其处理函数。language 的可选值取自创建时 e.SupportedLanguages()，
+// 工具描述与错误提示使用 e 的 Locale。
+//
+// 代码本身的失败（编译错误、运行时错误、触发资源限制等）是正常的工具结果，由 error_kind 区分；
+// 不支持的语言、参数非法、超时、执行器繁忙或不可用等无法给出代码运行结果的情况以 isError 返回，
+// 文本内容为错误描述。两种情况的 structuredContent 都是完整的 ExecutionResult
+func NewTool(e *sandbox.CodeExecutor) (Tool, ToolHandler) {
+	tool := Tool{
+		Name:         ToolName,
+		Description:  e.Message(sandbox.MsgToolDescription),
+		InputSchema:  inputSchema(e),
+		OutputSchema: outputSchema(e),
+	}
+	handler := func(ctx context.Context, arguments json.RawMessage) CallToolResult {
+		var args toolArguments
+		if err := json.Unmarshal(arguments, &args); err != nil {
+			return CallToolResult{Content: []Content{{Type: "text", Text: e.Message(sandbox.MsgToolBadArguments, err)}}, IsError: true}
+		}
+		if args.Timeout < 0 {
+			return CallToolResult{Content: []Content{{Type: "text", Text: e.Message(sandbox.MsgToolNegativeTimeout)}}, IsError: true}
+		}
+
+		opts := sandbox.ExecOptions{Stdin: args.Stdin, Timeout: time.Duration(args.Timeout * float64(time.Second))}
+		result := e.ExecuteContextWithOptions(ctx, args.Code, args.Language, opts)
+		if isToolError(result.ErrorKind) {
+			return CallToolResult{Content: []Content{{Type: "text", Text: result.Error}}, StructuredContent: result, IsError: true}
+		}
+		text, _ := json.Marshal(result)
+		return CallToolResult{Content: []Content{{Type: "text", Text: string(text)}}, StructuredContent: result}
+	}
+	return tool, handler
+}
+
+// isToolError 判断该分类的失败是否意味着代码没有得到运行结果，应作为工具错误返回
+func isToolError(kind sandbox.ErrorKind) bool {
+	switch kind {
+	case sandbox.ErrorKindInvalidRequest, sandbox.ErrorKindLanguageUnsupported, sandbox.ErrorKindCodeTooLarge,
+		sandbox.ErrorKindTimeout, sandbox.ErrorKindCanceled, sandbox.ErrorKindBusy, sandbox.ErrorKindRateLimited,
+		sandbox.ErrorKindQueueFull, sandbox.ErrorKindRuntimeUnavailable, sandbox.ErrorKindShuttingDown,
+		sandbox.ErrorKindInternal:
+		return true
+	}
+	return false
+}
+
+// inputSchema 返回工具参数的 JSON Schema，language 的可选值取自 e.SupportedLanguages()，描述文字取自 e 的消息目录
+func inputSchema(e *sandbox.CodeExecutor) json.RawMessage {
+	schema := map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"code":     map[string]any{"type": "string", "description": e.Message(sandbox.MsgToolParamCode)},
+			"language": map[string]any{"type": "string", "enum": e.SupportedLanguages(), "description": e.Message(sandbox.MsgToolParamLanguage)},
+			"stdin":    map[string]any{"type": "string", "description": e.Message(sandbox.MsgToolParamStdin)},
+			"timeout":  map[string]any{"type": "number", "minimum": 0, "description": e.Message(sandbox.MsgToolParamTimeout)},
+		},
+		"required":             []string{"code", "language"},
+		"additionalProperties": false,
+	}
+	data, _ := json.Marshal(schema)
+	return data
+}
+
+// outputSchema 返回 sandbox.ExecutionResult 的 JSON Schema，描述文字取自 e 的消息目录
+func outputSchema(e *sandbox.CodeExecutor) json.RawMessage {
+	described := func(schema map[string]any, id sandbox.MessageID) map[string]any {
+		schema["description"] = e.Message(id)
+		return schema
+	}
+	typed := func(kind string) map[string]any { return map[string]any{"type": kind} }
+	schema := map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"id":              described(typed("string"), sandbox.MsgToolResultID),
+			"success":         typed("boolean"),
+			"output":          described(typed("string"), sandbox.MsgToolResultOutput),
+			"error":           described(typed("string"), sandbox.MsgToolResultError),
+			"stderr":          described(typed("string"), sandbox.MsgToolResultStderr),
+			"error_kind":      described(typed("string"), sandbox.MsgToolResultErrorKind),
+			"exit_code":       typed("integer"),
+			"signal":          described(typed("string"), sandbox.MsgToolResultSignal),
+			"error_phase":     map[string]any{"type": "string", "enum": []string{"setup", "install", "compile", "runtime", "timeout"}},
+			"duration_ms":     typed("integer"),
+			"truncated":       described(typed("boolean"), sandbox.MsgToolResultTruncated),
+			"invalid_utf8":    typed("boolean"),
+			"max_rss_bytes":   typed("integer"),
+			"cpu_time_ms":     typed("integer"),
+			"language":        typed("string"),
+			"runtime_version": typed("string"),
+			"attempts":        typed("integer"),
+			"started_at":      map[string]any{"type": "string", "format": "date-time"},
+			"finished_at":     map[string]any{"type": "string", "format": "date-time"},
+			"queue_wait_ms":   typed("integer"),
+		},
+		"required": []string{"id", "success", "output", "error", "exit_code", "duration_ms", "truncated", "language"},
+	}
+	data, _ := json.Marshal(schema)
+	return data
+}
@@ -0,0 +1,467 @@
+package sandbox
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// pythonSessionDriver 从 fd 3 依次读取单元格并在同一个命名空间中执行，状态在单元格之间保留。
+// 第一行是结束标记，之后每个单元格以 "<字节数>\n" 开头。单元格的最后一个语句是表达式时像 REPL
+// 一样打印其 repr。每个单元格执行完后向 stdout 与 stderr 写入结束标记，再向 fd 4 写入 0（成功）或 1（抛出异常）
+const pythonSessionDriver = `import ast as _ast, linecache as _linecache, os as _os, sys as _sys, traceback as _traceback
+
+def _session():
+    cells = _os.fdopen(3, "rb")
+    status = _os.fdopen(4, "w")
+    token = cells.readline().rstrip(b"\n")
+    namespace = {"__name__": "__main__", "__builtins__": __builtins__}
+    count = 0
+    while True:
+        header = cells.readline()
+        if not header:
+            return
+        source = cells.read(int(header)).decode("utf-8")
+        count += 1
+        filename = "<cell-%d>" % count
+        _linecache.cache[filename] = (len(source), None, source.splitlines(True), filename)
+        failed = False
+        try:
+            tree = _ast.parse(source, filename)
+            last = None
+            if tree.body and isinstance(tree.body[-1], _ast.Expr):
+                last = _ast.Expression(tree.body.pop().value)
+            exec(compile(tree, filename, "exec"), namespace)
+            if last is not None:
+                value = eval(compile(last, filename, "eval"), namespace)
+                if value is not None:
+                    namespace["_"] = value
+                    print(repr(value))
+        except SystemExit:
+            raise
+        except BaseException:
+            kind, value, tb = _sys.exc_info()
+            _traceback.print_exception(kind, value, tb.tb_next)
+            failed = True
+        for stream in (_sys.stdout, _sys.stderr):
+            try:
+                stream.flush()
+            except Exception:
+                pass
+        _os.write(1, token)
+        _os.write(2, token)
+        status.write("1\n" if failed else "0\n")
+        status.flush()
+
+_sys.argv[0] = "<cell>"
+_session()
+`
+
+// nodeSessionDriver 是 pythonSessionDriver 的 Node.js 版本：单元格在同一个全局上下文中执行，
+// 最后一个表达式的值不为 undefined 时打印；值为 Promise 时等待其完成后才结束该单元格。
+// fd 3 以非阻塞的 net.Socket 读取，fs.createReadStream 会让线程池阻塞在 read 上，使 process.exit 无法退出
+const nodeSessionDriver = `(() => {
+  const fs = require('fs');
+  const vm = require('vm');
+  const util = require('util');
+  globalThis.require = require;
+  let buffer = Buffer.alloc(0);
+  let token = null;
+  let queue = Promise.resolve();
+  const run = async (source, filename) => {
+    try {
+      let value = vm.runInThisContext(source, { filename });
+      if (value instanceof Promise) value = await value;
+      if (value !== undefined) console.log(util.inspect(value));
+      return false;
+    } catch (err) {
+      const text = err && err.stack ? err.stack : String(err);
+      console.error(text.split('\n').filter((line) => !/^\s+at .*(node:|\[eval\])/.test(line)).join('\n'));
+      return true;
+    }
+  };
+  const finish = (failed) => {
+    fs.writeSync(1, token);
+    fs.writeSync(2, token);
+    fs.writeSync(4, failed ? '1\n' : '0\n');
+  };
+  let count = 0;
+  new (require('net').Socket)({ fd: 3, readable: true, writable: false }).on('data', (chunk) => {
+    buffer = Buffer.concat([buffer, chunk]);
+    for (;;) {
+      const newline = buffer.indexOf(10);
+      if (newline < 0) return;
+      const header = buffer.subarray(0, newline).toString();
+      if (token === null) {
+        token = header;
+        buffer = buffer.subarray(newline + 1);
+        continue;
+      }
+      const end = newline + 1 + Number(header);
+      if (buffer.length < end) return;
+      const source = buffer.subarray(newline + 1, end).toString();
+      buffer = buffer.subarray(end);
+      const filename = '<cell-' + ++count + '>';
+      queue = queue.then(() => run(source, filename)).then(finish);
+    }
+  });
+})();
+`
+
+// Session 是保留状态的交互式会话：一个常驻的 Python 或 Node.js 解释器依次执行多个单元格，
+// 前面单元格定义的变量、函数和导入的模块在后面的单元格中仍然可用，适合笔记本式的界面。
+// Session 的方法可被并发调用，单元格按调用顺序逐个执行
+type Session struct {
+	e        *CodeExecutor
+	language string
+	opts     ExecOptions
+
+	mu             sync.Mutex // 串行化单元格的执行
+	cmd            *exec.Cmd
+	kill           context.CancelFunc // 终止进程组
+	cells          *os.File           // fd 3 管道的写端
+	status         *bufio.Reader      // fd 4 管道的读端
+	stdout, stderr *markerWriter
+	done           chan struct{} // 进程退出且输出读完后关闭
+	err            error         // Wait 的返回值，done 关闭后可读
+}
+
+// sessionCommand 返回 language 的会话解释器与参数，不支持会话的语言返回 ok=false
+func (e *CodeExecutor) sessionCommand(language string, opts ExecOptions) (name string, args []string, ok bool) {
+	switch language {
+	case "python3":
+		return e.pythonInterpreter(opts.PythonVersion), []string{"-c", pythonSessionDriver}, true
+	case "nodejs":
+		return e.nodePath, []string{"-e", nodeSessionDriver}, true
+	}
+	return "", nil, false
+}
+
+// NewSession 启动 language（python3 或 nodejs）的会话解释器。opts 中的 Env、InheritEnv、WorkDir
+// 与 PythonVersion 作用于整个会话，Timeout 作用于每个单元格，其余字段被忽略。
+// 解释器以与普通执行相同的运行用户、网络隔离和资源限制启动；MaxCPUSeconds 等按进程计算的限制
+// 由所有单元格共同消耗。单元格超时、输出超限或解释器退出（如调用 exit()）后会话随之结束，
+// 之后的 Execute 以 ErrorKindInvalidRequest 失败。Shutdown 会关闭所有会话。
+// Docker 后端与不支持向子进程传递额外文件描述符的平台（如 Windows）返回 errors.ErrUnsupported
+func (e *CodeExecutor) NewSession(language string, opts ExecOptions) (*Session, error) {
+	if !e.longLivedSupported() {
+		return nil, errors.ErrUnsupported
+	}
+	name, args, ok := e.sessionCommand(language, opts)
+	if !ok {
+		return nil, e.sandboxError(ErrorKindLanguageUnsupported, MsgSessionUnsupported, language)
+	}
+	if err := e.unavailableError(language); err != nil {
+		return nil, err
+	}
+	workDir, err := e.prepareWorkDir(opts.WorkDir)
+	if err != nil {
+		return nil, err
+	}
+	opts.WorkDir = workDir
+	opts.Stdin, opts.Args = "", nil
+	opts.pythonLib = language == "python3"
+
+	e.mu.Lock()
+	closing, closed := e.closing, e.closed
+	e.mu.Unlock()
+	if closing {
+		return nil, e.sandboxError(ErrorKindShuttingDown, MsgShuttingDown)
+	}
+
+	ctx, kill := context.WithCancel(context.Background())
+	cmd := exec.CommandContext(ctx, name, args...)
+	if err := e.prepareUserCommand(cmd, opts); err != nil {
+		kill()
+		return nil, err
+	}
+
+	cellReader, cellWriter, err := os.Pipe()
+	if err != nil {
+		kill()
+		return nil, err
+	}
+	defer cellReader.Close()
+	statusReader, statusWriter, err := os.Pipe()
+	if err != nil {
+		kill()
+		cellWriter.Close()
+		return nil, err
+	}
+	defer statusWriter.Close()
+
+	token := "\x1esandbox-cell-" + newExecutionID() + "\x1e"
+	s := &Session{
+		e:        e,
+		language: language,
+		opts:     opts,
+		cmd:      cmd,
+		kill:     kill,
+		cells:    cellWriter,
+		status:   bufio.NewReader(statusReader),
+		stdout:   newMarkerWriter(token),
+		stderr:   newMarkerWriter(token),
+		done:     make(chan struct{}),
+	}
+	cmd.ExtraFiles = []*os.File{cellReader, statusWriter}
+	cmd.Stdout = s.stdout
+	cmd.Stderr = s.stderr
+	stop := setupProcessGroup(cmd, e.killGrace)
+	// 进程组被杀死后，残留的孙进程可能仍持有输出管道，避免 Wait 无限阻塞
+	cmd.WaitDelay = e.killGrace + time.Second
+	if err := cmd.Start(); err != nil {
+		kill()
+		cellWriter.Close()
+		statusReader.Close()
+		return nil, err
+	}
+	// 管道容量足以容纳标记，解释器会在读取第一个单元格之前读走它
+	io.WriteString(cellWriter, token+"\n")
+
+	go func() {
+		s.err = cmd.Wait()
+		stop()
+		kill()
+		cellWriter.Close()
+		statusReader.Close()
+		close(s.done)
+	}()
+	go func() {
+		select {
+		case <-closed:
+			// 等正在执行的单元格结束后再关闭，与 Shutdown 等待已接受的执行一致
+			s.mu.Lock()
+			s.Close()
+			s.mu.Unlock()
+		case <-s.done:
+		}
+	}()
+	return s, nil
+}
+
+// Execute 在会话中执行一个单元格，返回其输出。Output 只包含本单元格产生的输出
+func (s *Session) Execute(code string) ExecutionResult {
+	return s.ExecuteContext(context.Background(), code)
+}
+
+// ExecuteContext 与 Execute 相同，ctx 被取消时终止会话
+func (s *Session) ExecuteContext(ctx context.Context, code string) ExecutionResult {
+	e := s.e
+	id := newExecutionID()
+	result := sanitizeUTF8(s.execute(ctx, id, code))
+	result.ID = id
+	result.Language = s.language
+	result.ErrorPhase = phaseOf(result)
+	if result.ErrorPhase != PhaseSetup {
+		result.RuntimeVersion = e.runtimeVersionOf(s.language, s.opts)
+	}
+	e.finish(s.language, result, false)
+	return result
+}
+
+// execute 是 ExecuteContext 的实现，结果中的 ID 等公共字段由调用方填写
+func (s *Session) execute(parent context.Context, id string, code string) (result ExecutionResult) {
+	e := s.e
+	if e.codeTooLarge(len(code)) {
+		return e.fail(ErrorKindCodeTooLarge, MsgCodeTooLarge, e.maxCodeBytes)
+	}
+	if !e.admit() {
+		return e.fail(ErrorKindShuttingDown, MsgShuttingDown)
+	}
+	defer e.inflight.Done()
+
+	tracked, cancel := context.WithCancel(parent)
+	defer cancel()
+	if !e.track(id, cancel) {
+		return e.fail(ErrorKindInvalidRequest, MsgDuplicateID, id)
+	}
+	defer e.untrack(id)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.exited() {
+		return e.fail(ErrorKindInvalidRequest, MsgSessionClosed)
+	}
+
+	e.queued.Add(1)
+	queuedAt := time.Now()
+	err := e.acquireWorker(tracked, s.opts.Priority)
+	queueWait := time.Since(queuedAt)
+	e.queued.Add(-1)
+	defer func() { result.QueueWaitMs = queueWait.Milliseconds() }()
+	if errors.Is(err, errPoolBusy) {
+		return e.fail(ErrorKindBusy, MsgBusy)
+	}
+	timeout := e.timeoutFor(s.language, s.opts.Timeout)
+	if err != nil {
+		return e.canceledResult(tracked, timeout, 0)
+	}
+	defer e.releaseWorker()
+	e.emit(ExecEvent{Type: EventStarted, ID: id, Language: s.language})
+
+	ctx, cancelTimeout := context.WithTimeout(tracked, timeout)
+	defer cancelTimeout()
+
+	output := newCommandOutput(s.cmd, e.maxOutputBytes, nil, nil, true)
+	s.stdout.begin(output.stdoutW)
+	s.stderr.begin(output.stderrW)
+	defer s.stdout.begin(nil)
+	defer s.stderr.begin(nil)
+
+	statusChan := make(chan string, 1)
+	start := time.Now()
+	defer func() { result.StartedAt, result.FinishedAt = start, time.Now() }()
+	go func() {
+		// 解释器退出时写入失败，结果由其退出状态体现
+		io.WriteString(s.cells, strconv.Itoa(len(code))+"\n"+code)
+		line, _ := s.status.ReadString('\n')
+		statusChan <- strings.TrimSpace(line)
+	}()
+
+	select {
+	case status := <-statusChan:
+		if status == "" {
+			// 状态管道被关闭，说明解释器在执行单元格期间退出
+			<-s.done
+			return s.exitResult(output, time.Since(start))
+		}
+		// 标记在状态之前写出，等待输出拷贝协程把它们读完
+		for _, w := range []*markerWriter{s.stdout, s.stderr} {
+			select {
+			case <-w.marked:
+			case <-s.done:
+				return s.exitResult(output, time.Since(start))
+			}
+		}
+		return s.cellResult(output, status == "1", time.Since(start))
+	case <-s.done:
+		return s.exitResult(output, time.Since(start))
+	case <-ctx.Done():
+		// 单元格无法被单独中断，超时或取消时终止整个会话。WaitDelay 保证进程在宽限期后不久退出
+		s.kill()
+		<-s.done
+		result := e.canceledResult(tracked, timeout, time.Since(start))
+		result.ExitCode, result.Signal = exitCodeOf(s.cmd), terminationSignal(s.cmd.ProcessState)
+		result.Output, result.Stderr = output.stdout.String(), output.stderr.String()
+		if result.Stderr != "" {
+			result.Error = result.Stderr + "\n" + result.Error
+		}
+		return result
+	}
+}
+
+// cellResult 构造正常结束的单元格的结果，failed 表示单元格抛出了未捕获的异常
+func (s *Session) cellResult(output *commandOutput, failed bool, elapsed time.Duration) ExecutionResult {
+	stdout, stderr := output.stdout.String(), output.stderr.String()
+	result := ExecutionResult{
+		Success:    !failed,
+		Output:     stdout,
+		Stderr:     stderr,
+		DurationMs: elapsed.Milliseconds(),
+	}
+	if failed {
+		result.Error = stderr
+		result.ErrorKind = ErrorKindRuntimeError
+		result.ExitCode = 1
+	}
+	return result
+}
+
+// exitResult 构造解释器在单元格执行期间退出时的结果，会话随之结束
+func (s *Session) exitResult(output *commandOutput, elapsed time.Duration) ExecutionResult {
+	result := s.e.commandResult(s.cmd, output, s.err, elapsed)
+	if result.Success {
+		// 解释器以状态 0 退出（如调用 exit()），单元格本身没有出错，但会话已经结束
+		result.Error = s.e.msg(MsgSessionClosed)
+	}
+	return s.e.annotateLimits(result, s.cmd.ProcessState)
+}
+
+// exited 报告会话的解释器是否已经退出
+func (s *Session) exited() bool {
+	select {
+	case <-s.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// Close 终止会话的解释器并等待其退出，之后的 Execute 以 ErrorKindInvalidRequest 失败。可重复调用
+func (s *Session) Close() error {
+	s.kill()
+	<-s.done
+	return nil
+}
+
+// markerWriter 把会话解释器的输出转发给当前单元格的 Writer，并从中去掉单元格的结束标记。
+// 每遇到一次标记就向 marked 发送一次信号，并停止转发，直到下一个单元格调用 begin
+type markerWriter struct {
+	mu      sync.Mutex
+	token   []byte
+	w       io.Writer
+	pending []byte // 可能是标记开头的未转发字节
+	marked  chan struct{}
+}
+
+func newMarkerWriter(token string) *markerWriter {
+	return &markerWriter{token: []byte(token), marked: make(chan struct{}, 1)}
+}
+
+// begin 把后续输出转发给 w，w 为 nil 时丢弃输出
+func (m *markerWriter) begin(w io.Writer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.w = w
+	select {
+	case <-m.marked:
+	default:
+	}
+}
+
+// Write 实现 io.Writer，始终返回 len(p)
+func (m *markerWriter) Write(p []byte) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	buf := append(m.pending, p...)
+	for {
+		i := bytes.Index(buf, m.token)
+		if i < 0 {
+			break
+		}
+		m.forward(buf[:i])
+		buf = buf[i+len(m.token):]
+		m.w = nil
+		select {
+		case m.marked <- struct{}{}:
+		default:
+		}
+	}
+	// 末尾可能是被拆开的标记，留到下次写入时再判断
+	keep := 0
+	for n := min(len(buf), len(m.token)-1); n > 0; n-- {
+		if bytes.HasPrefix(m.token, buf[len(buf)-n:]) {
+			keep = n
+			break
+		}
+	}
+	m.forward(buf[:len(buf)-keep])
+	m.pending = append([]byte(nil), buf[len(buf)-keep:]...)
+	return len(p), nil
+}
+
+func (m *markerWriter) forward(p []byte) {
+	if m.w != nil && len(p) > 0 {
+		m.w.Write(p)
+	}
+}
@@ -0,0 +1,75 @@
+package sandbox
+
+import (
+	"context"
+	"sync"
+)
+
+// admit 在执行器未关闭时登记一个进行中的执行，之后必须调用 e.inflight.Done。
+// 执行器已关闭时返回 false
+func (e *CodeExecutor) admit() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.closing {
+		return false
+	}
+	e.inflight.Add(1)
+	return true
+}
+
+// Shutdown 停止接受新的执行，并等待已接受的执行（包括排队中的和通过 Submit 提交的任务）结束。
+// ctx 先结束时返回 ctx.Err()，此时剩余的执行仍会在后台继续完成。
+// 关闭后的 Execute 等方法以 ErrorKindShuttingDown 失败，Submit 与 ExecuteStream 返回 ErrShuttingDown。
+// 空闲的预热进程会被立即终止。可重复调用，之后可以用 Restart 恢复
+func (e *CodeExecutor) Shutdown(ctx context.Context) error {
+	e.mu.Lock()
+	if !e.closing {
+		e.closing = true
+		close(e.closed)
+	}
+	inflight := e.inflight
+	e.mu.Unlock()
+	e.stopWarm()
+
+	drained := make(chan struct{})
+	go func() {
+		inflight.Wait()
+		close(drained)
+	}()
+
+	select {
+	case <-drained:
+		if e.compileCache != nil {
+			e.compileCache.close()
+		}
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Restart 让 Shutdown 之后的执行器重新接受执行：先等待关闭前已接受的执行结束，再恢复 RuntimeRefreshInterval
+// 的后台刷新。预热进程与会话不会恢复，需要时重新调用 Warmup 与 NewSession。执行器未关闭时为空操作
+func (e *CodeExecutor) Restart() {
+	e.mu.Lock()
+	closing, inflight := e.closing, e.inflight
+	e.mu.Unlock()
+	if !closing {
+		return
+	}
+	// 关闭期间不会登记新的执行。提前返回的 Shutdown 可能仍在等待旧的 WaitGroup，之后的执行改用新的
+	inflight.Wait()
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if !e.closing || e.inflight != inflight {
+		return
+	}
+	e.closing = false
+	e.closed = make(chan struct{})
+	e.inflight = &sync.WaitGroup{}
+	if e.refreshEvery > 0 {
+		go e.pollRuntimes(e.refreshEvery, e.closed)
+	}
+}
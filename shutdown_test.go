@@ -0,0 +1,84 @@
+package sandbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// finishedWithin 判断 running 是否在 wait 内给出结果，用于检查执行已在 Shutdown 或 Restart 返回前结束：
+// 结果在 Execute 返回后才发送，留出很短的时间
+func finishedWithin(t *testing.T, running <-chan ExecutionResult, wait time.Duration) bool {
+	t.Helper()
+	select {
+	case result := <-running:
+		if !result.Success {
+			t.Errorf("Shutdown 前开始的执行失败: %s", result.Error)
+		}
+		return true
+	case <-time.After(wait):
+		return false
+	}
+}
+
+func TestShutdownAndRestart(t *testing.T) {
+	tests := []struct {
+		name         string
+		work         time.Duration // Shutdown 前开始的执行的时长，0 表示没有进行中的执行
+		shutdownWait time.Duration // Shutdown 的 ctx 期限
+		wantErr      error
+	}{
+		{"idle", 0, 5 * time.Second, nil},
+		{"waits for running work", time.Second, 10 * time.Second, nil},
+		{"deadline before work ends", 2 * time.Second, 100 * time.Millisecond, context.DeadlineExceeded},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newTestExecutor(t, "python3", Config{})
+			running := make(chan ExecutionResult, 1)
+			if tt.work > 0 {
+				code := fmt.Sprintf("import time\ntime.sleep(%g)\nprint('done')", tt.work.Seconds())
+				go func() { running <- e.Execute(code, "python3") }()
+				for deadline := time.Now().Add(5 * time.Second); e.Stats().InFlight == 0; time.Sleep(10 * time.Millisecond) {
+					if time.Now().After(deadline) {
+						t.Fatal("执行没有开始")
+					}
+				}
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), tt.shutdownWait)
+			defer cancel()
+			if err := e.Shutdown(ctx); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Shutdown 返回 %v，期望 %v", err, tt.wantErr)
+			}
+			if tt.work > 0 && tt.wantErr == nil {
+				if !finishedWithin(t, running, 200*time.Millisecond) {
+					t.Error("Shutdown 在进行中的执行结束前返回")
+				}
+			}
+
+			done := make(chan ExecutionResult, 1)
+			go func() { done <- e.Execute(`print("after")`, "python3") }()
+			select {
+			case result := <-done:
+				if !errors.Is(result.Err(), ErrShuttingDown) {
+					t.Fatalf("关闭后执行的错误 = %v，期望 ErrShuttingDown", result.Err())
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("关闭后的 Execute 没有返回")
+			}
+
+			e.Restart()
+			if tt.work > 0 && tt.wantErr != nil {
+				if !finishedWithin(t, running, 200*time.Millisecond) {
+					t.Error("Restart 在关闭前已接受的执行结束前返回")
+				}
+			}
+			if result := e.Execute(`print("restarted")`, "python3"); !result.Success || result.Output != "restarted\n" {
+				t.Fatalf("Restart 后执行失败: %s", result.Error)
+			}
+		})
+	}
+}
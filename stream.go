@@ -0,0 +1,108 @@
+package sandbox
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"sync"
+)
+
+// 输出流标识
+const (
+	StreamStdout = "stdout"
+	StreamStderr = "stderr"
+)
+
+// OutputChunk 是流式执行过程中产生的一段输出。
+// 通道关闭前的最后一个块 Stream 为空，Result 携带最终的执行结果
+type OutputChunk struct {
+	Stream string           `json:"stream,omitempty"`
+	Data   []byte           `json:"data,omitempty"`
+	Result *ExecutionResult `json:"result,omitempty"`
+}
+
+// ExecuteStream 执行代码并按行实时返回输出，进程退出后通道关闭。
+// 语言不支持、不可用或执行器已关闭时直接返回 *SandboxError。调用方应持续读取直到通道关闭，
+// 若 ctx 被取消则最终结果可能被丢弃
+func (e *CodeExecutor) ExecuteStream(ctx context.Context, code string, language string) (<-chan OutputChunk, error) {
+	return e.ExecuteStreamWithOptions(ctx, code, language, ExecOptions{})
+}
+
+// ExecuteStreamWithOptions 与 ExecuteStream 相同，并按 opts 指定的单次执行选项执行代码
+func (e *CodeExecutor) ExecuteStreamWithOptions(ctx context.Context, code string, language string, opts ExecOptions) (<-chan OutputChunk, error) {
+	if err := e.unavailableError(language); err != nil {
+		return nil, err
+	}
+	if !e.admit() {
+		return nil, e.sandboxError(ErrorKindShuttingDown, MsgShuttingDown)
+	}
+
+	chunks := make(chan OutputChunk, 64)
+	stdoutR, stdoutW := io.Pipe()
+	stderrR, stderrW := io.Pipe()
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go forwardLines(ctx, stdoutR, StreamStdout, chunks, &wg)
+	go forwardLines(ctx, stderrR, StreamStderr, chunks, &wg)
+
+	go func() {
+		defer e.inflight.Done()
+		opts.stdout, opts.stderr, opts.admitted = stdoutW, stderrW, true
+		result := e.execute(ctx, code, language, opts)
+		stdoutW.Close()
+		stderrW.Close()
+		wg.Wait()
+
+		select {
+		case chunks <- OutputChunk{Result: &result}:
+		case <-ctx.Done():
+		}
+		close(chunks)
+	}()
+
+	return chunks, nil
+}
+
+// ExecuteTo 执行代码并把 stdout、stderr 实时写入给定的 Writer，适合输出较大、不宜在内存中缓冲的场景，
+// 如直接写入 http.ResponseWriter。stdout 或 stderr 为 nil 时丢弃对应的输出。
+// 输出仍受 MaxOutputBytes 限制；返回结果的 Output 始终为空，Stderr 与 Error 照常保留标准错误以便诊断。
+// Writer 在子进程的输出协程中被调用，写入阻塞会拖慢子进程；ExecuteTo 返回后不会再写入
+func (e *CodeExecutor) ExecuteTo(ctx context.Context, code string, language string, stdout, stderr io.Writer) ExecutionResult {
+	if stdout == nil {
+		stdout = io.Discard
+	}
+	// 超时后被终止的进程组可能仍有输出在途，返回前切断 Writer
+	stdoutGate := &switchWriter{w: stdout}
+	defer stdoutGate.set(nil)
+	opts := ExecOptions{stdout: stdoutGate, discardStdout: true}
+	if stderr != nil {
+		stderrGate := &switchWriter{w: stderr}
+		defer stderrGate.set(nil)
+		opts.stderr = stderrGate
+	}
+	return e.execute(ctx, code, language, opts)
+}
+
+// forwardLines 从 r 中逐行读取输出并发送到 chunks，直到 r 关闭。
+// ctx 被取消后不再发送，但继续读空 r，避免写入方阻塞
+func forwardLines(ctx context.Context, r *io.PipeReader, stream string, chunks chan<- OutputChunk, wg *sync.WaitGroup) {
+	defer wg.Done()
+	defer r.Close()
+
+	reader := bufio.NewReader(r)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(line) > 0 {
+			select {
+			case chunks <- OutputChunk{Stream: stream, Data: line}:
+			case <-ctx.Done():
+				io.Copy(io.Discard, reader)
+				return
+			}
+		}
+		if err != nil {
+			return
+		}
+	}
+}
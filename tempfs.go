@@ -0,0 +1,80 @@
+package sandbox
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"runtime"
+	"time"
+)
+
+// TempFS 是执行器创建、写入与删除临时代码文件和临时目录所用的文件系统，默认为 OSFS。
+// 解释器与编译器按路径读取这些文件，因此实现返回的路径必须能被子进程打开，
+// 如包装 OSFS 以记录清理情况、注入失败，或把文件放在 tmpfs 挂载点上；
+// 纯内存的实现只适合检查创建失败与清理逻辑，代码本身无法运行。
+// 用户指定的 WorkDir、输入输出文件与依赖缓存不经过 TempFS。实现必须可被并发调用
+type TempFS interface {
+	// CreateTemp 在 dir 中创建名称匹配 pattern 的新文件，语义同 os.CreateTemp
+	CreateTemp(dir string, pattern string) (TempFile, error)
+	// MkdirTemp 在 dir 中创建名称匹配 pattern 的新目录并返回其路径，语义同 os.MkdirTemp
+	MkdirTemp(dir string, pattern string) (string, error)
+	// OpenFile 按 flag 与 perm 打开临时目录中的文件，语义同 os.OpenFile
+	OpenFile(name string, flag int, perm fs.FileMode) (TempFile, error)
+	// RemoveAll 删除 path 及其包含的全部内容，path 不存在时返回 nil
+	RemoveAll(path string) error
+}
+
+// TempFile 是 TempFS 打开的可写文件，*os.File 实现了该接口
+type TempFile interface {
+	io.WriteCloser
+	Name() string
+}
+
+// OSFS 是直接使用 os 包操作本地磁盘的 TempFS
+type OSFS struct{}
+
+func (OSFS) CreateTemp(dir string, pattern string) (TempFile, error) {
+	return os.CreateTemp(dir, pattern)
+}
+
+func (OSFS) MkdirTemp(dir string, pattern string) (string, error) {
+	return os.MkdirTemp(dir, pattern)
+}
+
+func (OSFS) OpenFile(name string, flag int, perm fs.FileMode) (TempFile, error) {
+	return os.OpenFile(name, flag, perm)
+}
+
+func (OSFS) RemoveAll(path string) error {
+	return os.RemoveAll(path)
+}
+
+// writeTempFile 通过 e.tempFS 以 0600 权限把 data 写入临时目录中的文件 name
+func (e *CodeExecutor) writeTempFile(name string, data []byte) error {
+	file, err := e.tempFS.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// removeTemp 通过 e.tempFS 删除临时文件或目录
+func (e *CodeExecutor) removeTemp(path string) {
+	removeWithRetry(e.tempFS.RemoveAll, path)
+}
+
+// removeWithRetry 用 remove 删除 path。Windows 上杀毒软件与索引服务常会短暂占用刚写入的文件，
+// 删除失败时稍后重试几次，其他平台只尝试一次
+func removeWithRetry(remove func(string) error, path string) {
+	for attempt := 1; ; attempt++ {
+		err := remove(path)
+		if err == nil || runtime.GOOS != "windows" || attempt == 5 {
+			return
+		}
+		time.Sleep(time.Duration(attempt) * 50 * time.Millisecond)
+	}
+}
@@ -0,0 +1,318 @@
+package sandbox
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestRemoveWithRetry(t *testing.T) {
+	tests := []struct {
+		name     string
+		failures int // remove 在成功前失败的次数
+		want     int // 期望的调用次数（Windows）
+	}{
+		{"success", 0, 1},
+		{"transient lock", 2, 3},
+		{"persistent lock", 10, 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			remove := func(string) error {
+				calls++
+				if calls <= tt.failures {
+					return errors.New("file is locked")
+				}
+				return nil
+			}
+			removeWithRetry(remove, "snippet.py")
+			want := tt.want
+			if runtime.GOOS != "windows" {
+				want = 1
+			}
+			if calls != want {
+				t.Errorf("remove 调用了 %d 次，期望 %d 次", calls, want)
+			}
+		})
+	}
+}
+
+// faultyFS 包装 OSFS，写入其打开的文件时按 panics 返回错误或 panic，用于检查失败路径上的清理；
+// created 记录创建的临时文件与目录
+type faultyFS struct {
+	OSFS
+	panics  bool
+	created *[]string
+}
+
+func (f faultyFS) CreateTemp(dir string, pattern string) (TempFile, error) {
+	file, err := f.OSFS.CreateTemp(dir, pattern)
+	if err != nil {
+		return nil, err
+	}
+	*f.created = append(*f.created, file.Name())
+	return faultyFile{file, f.panics}, nil
+}
+
+func (f faultyFS) MkdirTemp(dir string, pattern string) (string, error) {
+	path, err := f.OSFS.MkdirTemp(dir, pattern)
+	if err == nil {
+		*f.created = append(*f.created, path)
+	}
+	return path, err
+}
+
+func (f faultyFS) OpenFile(name string, flag int, perm fs.FileMode) (TempFile, error) {
+	file, err := f.OSFS.OpenFile(name, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+	return faultyFile{file, f.panics}, nil
+}
+
+type faultyFile struct {
+	TempFile
+	panics bool
+}
+
+func (f faultyFile) Write(p []byte) (int, error) {
+	if f.panics {
+		panic("injected write panic")
+	}
+	return 0, errors.New("injected write failure")
+}
+
+// runRecovered 调用 fn，fn 发生 panic 时恢复并返回 panicked 为 true
+func runRecovered(fn func() ExecutionResult) (result ExecutionResult, panicked bool) {
+	defer func() {
+		if recover() != nil {
+			panicked = true
+		}
+	}()
+	return fn(), false
+}
+
+func TestWriteFailureLeavesNoTempFiles(t *testing.T) {
+	run := map[string]func(e *CodeExecutor, language string) ExecutionResult{
+		"Execute": func(e *CodeExecutor, language string) ExecutionResult {
+			return e.Execute(healthProbes[language], language)
+		},
+		"ExecuteReader": func(e *CodeExecutor, language string) ExecutionResult {
+			return e.ExecuteReader(context.Background(), strings.NewReader(healthProbes[language]), language)
+		},
+	}
+	tests := []struct {
+		name     string
+		language string
+		entry    string
+		panics   bool
+	}{
+		{"interpreted", "python3", "Execute", false},
+		{"interpreted panic", "python3", "Execute", true},
+		{"compiled", "go", "Execute", false},
+		{"compiled panic", "go", "Execute", true},
+		{"reader", "python3", "ExecuteReader", false},
+		// ExecuteReader 在调用方的 goroutine 中写入代码，panic 传给调用方，临时目录仍须在展开时删除
+		{"reader panic", "python3", "ExecuteReader", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			var created []string
+			e := newTestExecutor(t, tt.language, Config{TempDir: dir, TempFS: faultyFS{panics: tt.panics, created: &created}})
+			result, panicked := runRecovered(func() ExecutionResult { return run[tt.entry](e, tt.language) })
+			if !panicked && result.ErrorKind != ErrorKindInternal {
+				t.Fatalf("ErrorKind = %q，期望 %q（%s）", result.ErrorKind, ErrorKindInternal, result.Error)
+			}
+			if panicked && tt.entry != "ExecuteReader" {
+				t.Fatal("runner 中的 panic 应转换为内部错误")
+			}
+			if len(created) == 0 {
+				t.Fatal("没有通过 TempFS 创建临时文件")
+			}
+			for _, path := range created {
+				if filepath.Dir(path) != dir {
+					t.Errorf("临时文件 %s 不在 TempDir %s 中", path, dir)
+				}
+			}
+			entries, err := os.ReadDir(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, entry := range entries {
+				t.Errorf("写入失败后残留临时文件 %s", entry.Name())
+			}
+		})
+	}
+}
+
+// memFS 是只存在于内存中的 TempFS，子进程无法读取其中的文件，只用于检查创建失败与清理逻辑。
+// failCreate、failMkdir 非空时对应的操作返回该错误；written 记录每个文件关闭时的内容
+type memFS struct {
+	mu         sync.Mutex
+	files      map[string]*bytes.Buffer
+	dirs       map[string]bool
+	next       int
+	written    []string
+	failCreate error
+	failMkdir  error
+}
+
+func newMemFS() *memFS {
+	return &memFS{files: map[string]*bytes.Buffer{}, dirs: map[string]bool{}}
+}
+
+// tempName 按 os.CreateTemp 的规则用序号替换 pattern 中最后一个 "*"，调用时须持有 f.mu
+func (f *memFS) tempName(dir string, pattern string) string {
+	f.next++
+	n := strconv.Itoa(f.next)
+	if i := strings.LastIndex(pattern, "*"); i >= 0 {
+		return filepath.Join(dir, pattern[:i]+n+pattern[i+1:])
+	}
+	return filepath.Join(dir, pattern+n)
+}
+
+func (f *memFS) CreateTemp(dir string, pattern string) (TempFile, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.failCreate != nil {
+		return nil, f.failCreate
+	}
+	name := f.tempName(dir, pattern)
+	f.files[name] = &bytes.Buffer{}
+	return &memFile{fs: f, name: name}, nil
+}
+
+func (f *memFS) MkdirTemp(dir string, pattern string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.failMkdir != nil {
+		return "", f.failMkdir
+	}
+	name := f.tempName(dir, pattern)
+	f.dirs[name] = true
+	return name, nil
+}
+
+func (f *memFS) OpenFile(name string, flag int, perm fs.FileMode) (TempFile, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !f.dirs[filepath.Dir(name)] {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	if _, ok := f.files[name]; ok && flag&os.O_EXCL != 0 {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrExist}
+	}
+	if _, ok := f.files[name]; !ok || flag&os.O_TRUNC != 0 {
+		f.files[name] = &bytes.Buffer{}
+	}
+	return &memFile{fs: f, name: name}, nil
+}
+
+func (f *memFS) RemoveAll(path string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	prefix := path + string(filepath.Separator)
+	for name := range f.files {
+		if name == path || strings.HasPrefix(name, prefix) {
+			delete(f.files, name)
+		}
+	}
+	for name := range f.dirs {
+		if name == path || strings.HasPrefix(name, prefix) {
+			delete(f.dirs, name)
+		}
+	}
+	return nil
+}
+
+// entries 返回 memFS 中尚未删除的文件与目录
+func (f *memFS) entries() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var names []string
+	for name := range f.files {
+		names = append(names, name)
+	}
+	for name := range f.dirs {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+type memFile struct {
+	fs   *memFS
+	name string
+}
+
+func (m *memFile) Name() string { return m.name }
+
+func (m *memFile) Write(p []byte) (int, error) {
+	m.fs.mu.Lock()
+	defer m.fs.mu.Unlock()
+	buf, ok := m.fs.files[m.name]
+	if !ok {
+		return 0, &fs.PathError{Op: "write", Path: m.name, Err: fs.ErrClosed}
+	}
+	return buf.Write(p)
+}
+
+func (m *memFile) Close() error {
+	m.fs.mu.Lock()
+	defer m.fs.mu.Unlock()
+	if buf, ok := m.fs.files[m.name]; ok {
+		m.fs.written = append(m.fs.written, buf.String())
+	}
+	return nil
+}
+
+const goMemCode = "package main\n\nfunc main() { println(\"memfs\") }\n"
+
+func TestInMemoryTempFS(t *testing.T) {
+	injected := errors.New("injected failure")
+	tests := []struct {
+		name        string
+		language    string
+		code        string
+		failCreate  error
+		failMkdir   error
+		wantKind    ErrorKind // 为空时只要求执行失败：内存中的代码文件无法被子进程读取
+		wantWritten bool
+	}{
+		{"interpreted", "python3", `print("memfs")`, nil, nil, "", true},
+		{"interpreted create failure", "python3", `print("memfs")`, injected, nil, ErrorKindInternal, false},
+		{"compiled", "go", goMemCode, nil, nil, "", true},
+		{"compiled mkdir failure", "go", goMemCode, nil, injected, ErrorKindInternal, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tempFS := newMemFS()
+			tempFS.failCreate, tempFS.failMkdir = tt.failCreate, tt.failMkdir
+			e := newTestExecutor(t, tt.language, Config{TempFS: tempFS})
+			result := e.Execute(tt.code, tt.language)
+			if result.Success {
+				t.Fatal("内存中的代码文件不应能被执行")
+			}
+			if tt.wantKind != "" && result.ErrorKind != tt.wantKind {
+				t.Errorf("ErrorKind = %q，期望 %q（%s）", result.ErrorKind, tt.wantKind, result.Error)
+			}
+			if tt.wantWritten && !slices.Contains(tempFS.written, tt.code) {
+				t.Errorf("代码没有写入 TempFS，写入的内容为 %q", tempFS.written)
+			}
+			if left := tempFS.entries(); len(left) > 0 {
+				t.Errorf("执行结束后 TempFS 中残留 %q", left)
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package sandbox
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName 是执行器创建 Tracer 时使用的仪表库名称
+const tracerName = "github.com/open-mcp-app/mcp-server-sandbox"
+
+// newTracer 从 provider 创建执行器的 Tracer，provider 为空时使用全局 TracerProvider
+func newTracer(provider trace.TracerProvider) trace.Tracer {
+	if provider == nil {
+		provider = otel.GetTracerProvider()
+	}
+	return provider.Tracer(tracerName)
+}
+
+// startExecution 为一次执行创建根 span，调用方的 ctx 中已有 span 时作为其子 span
+func (e *CodeExecutor) startExecution(ctx context.Context, language string) (context.Context, trace.Span) {
+	return e.tracer.Start(ctx, "sandbox.execute", trace.WithAttributes(
+		attribute.String("sandbox.language", language),
+	))
+}
+
+// endExecution 把执行结果记录到 span 上并结束它
+func endExecution(span trace.Span, result ExecutionResult, cached bool) {
+	span.SetAttributes(
+		attribute.String("sandbox.id", result.ID),
+		attribute.Bool("sandbox.success", result.Success),
+		attribute.Int("sandbox.exit_code", result.ExitCode),
+		attribute.Int64("sandbox.duration_ms", result.DurationMs),
+		attribute.Bool("sandbox.timeout", result.ErrorKind == ErrorKindTimeout),
+		attribute.Bool("sandbox.truncated", result.Truncated),
+		attribute.Bool("sandbox.cached", cached),
+	)
+	if !result.Success {
+		span.SetAttributes(attribute.String("sandbox.error_kind", string(result.ErrorKind)))
+		if result.ErrorPhase != "" {
+			span.SetAttributes(attribute.String("sandbox.error_phase", string(result.ErrorPhase)))
+		}
+		span.SetStatus(codes.Error, string(result.ErrorKind))
+	}
+	span.End()
+}
+
+// startPhase 在 ctx 中的执行 span 下为一个阶段（排队、写入代码、编译、启动、等待）创建子 span。
+// ctx 中没有活动 span 时直接返回其中的空 span，不产生额外开销
+func startPhase(ctx context.Context, name string) (context.Context, trace.Span) {
+	parent := trace.SpanFromContext(ctx)
+	if !parent.SpanContext().IsValid() {
+		return ctx, parent
+	}
+	return parent.TracerProvider().Tracer(tracerName).Start(ctx, name)
+}
+
+// endPhase 结束阶段 span，err 非空时记录错误
+func endPhase(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
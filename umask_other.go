@@ -0,0 +1,11 @@
+//go:build !unix
+
+package sandbox
+
+import (
+	"os"
+	"os/exec"
+)
+
+// applyUmask 在没有 umask 的平台（Windows）上为空操作
+func applyUmask(cmd *exec.Cmd, mask os.FileMode) {}
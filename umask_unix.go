@@ -0,0 +1,19 @@
+//go:build unix
+
+package sandbox
+
+import (
+	"os"
+	"os/exec"
+	"strconv"
+)
+
+// umaskScript 设置 umask 后以 exec 替换为原命令，不留下额外的 shell 进程
+const umaskScript = `umask "$0" && exec "$@"`
+
+// applyUmask 通过 /bin/sh 包装 cmd，在 exec 原命令之前设置 umask，由其派生的所有子进程继承
+func applyUmask(cmd *exec.Cmd, mask os.FileMode) {
+	args := []string{"/bin/sh", "-c", umaskScript, "0" + strconv.FormatUint(uint64(mask.Perm()), 8), cmd.Path}
+	cmd.Args = append(args, cmd.Args[1:]...)
+	cmd.Path = "/bin/sh"
+}
@@ -0,0 +1,258 @@
+package sandbox
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"time"
+)
+
+// pythonWarmBootstrap 让预热的 Python 解释器从 fd 3 读取代码，并在全新的 __main__ 命名空间中执行
+const pythonWarmBootstrap = `import os as _os, sys as _sys
+with _os.fdopen(3, "rb") as _f:
+    _src = _f.read()
+_sys.argv[0] = "<sandbox>"
+del _os, _sys, _f
+exec(compile(_src, "<sandbox>", "exec"), {"__name__": "__main__", "__builtins__": __builtins__})
+`
+
+// nodeWarmBootstrap 让预热的 Node.js 从 fd 3 读取代码，并像 CommonJS 模块一样包装后执行
+const nodeWarmBootstrap = `(() => {
+  const code = require('fs').readFileSync(3, 'utf8');
+  const vm = require('vm');
+  vm.runInThisContext(require('module').wrap(code), { filename: '<sandbox>' })(exports, require, module, '<sandbox>', process.cwd());
+})();
+`
+
+// warmProcess 是已经启动、阻塞在 fd 3 上等待代码的解释器进程。
+// 每个进程只执行一次代码，执行后即退出，不会在两次执行之间保留状态
+type warmProcess struct {
+	cmd            *exec.Cmd
+	kill           context.CancelFunc // 终止进程组
+	code           *os.File           // fd 3 管道的写端，写完代码后关闭
+	stdin          io.WriteCloser
+	stdout, stderr *switchWriter
+	done           chan struct{} // 进程退出且输出读完后关闭
+	err            error         // Wait 的返回值，done 关闭后可读
+}
+
+// exited 报告进程是否已经退出
+func (p *warmProcess) exited() bool {
+	select {
+	case <-p.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// warmCommand 返回 language 的预热进程的解释器与参数，不支持预热的语言返回 ok=false
+func (e *CodeExecutor) warmCommand(language string) (name string, args []string, ok bool) {
+	switch language {
+	case "python3":
+		return e.pythonPath, []string{"-c", pythonWarmBootstrap}, true
+	case "nodejs":
+		return e.nodePath, []string{"-e", nodeWarmBootstrap}, true
+	}
+	return "", nil, false
+}
+
+// Warmup 为 Python 与 Node.js 各预先启动 n 个空闲的解释器进程，省去小段代码执行时的解释器启动开销。
+// 预热进程以与普通执行相同的运行用户、网络隔离和资源限制启动，每个进程只执行一次代码，
+// 用掉后在后台启动新的进程补足 n 个。n 小于等于 0 时终止所有空闲进程并停止补充。
+//
+// 只有不指定 WorkDir、Env、InheritEnv、PythonVersion、Args、Packages、CombineOutput 且不是多文件项目的执行会使用预热进程，
+// 其余执行以及没有空闲进程时照常启动新的解释器。由预热进程执行时，错误栈中的文件名为 "<sandbox>"。
+// Docker 后端与不支持向子进程传递额外文件描述符的平台（如 Windows）返回 errors.ErrUnsupported
+func (e *CodeExecutor) Warmup(n int) error {
+	if !e.longLivedSupported() {
+		return errors.ErrUnsupported
+	}
+	e.mu.Lock()
+	closing := e.closing
+	e.mu.Unlock()
+	if closing {
+		return e.sandboxError(ErrorKindShuttingDown, MsgShuttingDown)
+	}
+
+	e.warmMu.Lock()
+	e.warmTarget = max(n, 0)
+	var extra []*warmProcess
+	for language, idle := range e.warm {
+		if len(idle) > e.warmTarget {
+			extra = append(extra, idle[e.warmTarget:]...)
+			e.warm[language] = idle[:e.warmTarget]
+		}
+	}
+	e.warmMu.Unlock()
+	for _, proc := range extra {
+		proc.kill()
+	}
+
+	for _, language := range []string{"python3", "nodejs"} {
+		if r := e.runner(language); r == nil || !r.Available() {
+			continue
+		}
+		for e.warmShortage(language) > 0 {
+			if err := e.addWarm(language); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// longLivedSupported 报告能否启动在宿主机上常驻、通过额外文件描述符接收代码的解释器进程，
+// 预热进程与 Session 都依赖于此
+func (e *CodeExecutor) longLivedSupported() bool {
+	return warmSupported && (e.backend == "" || e.backend == BackendProcess || e.backend == BackendBubblewrap)
+}
+
+// warmShortage 返回 language 的空闲预热进程距离 Warmup 要求的数量还差几个
+func (e *CodeExecutor) warmShortage(language string) int {
+	e.warmMu.Lock()
+	defer e.warmMu.Unlock()
+
+	return e.warmTarget - len(e.warm[language])
+}
+
+// addWarm 启动一个 language 的预热进程并放入空闲池，池已满或 Warmup 已被撤销时直接终止新进程
+func (e *CodeExecutor) addWarm(language string) error {
+	proc, err := e.startWarm(language)
+	if err != nil {
+		return err
+	}
+
+	e.warmMu.Lock()
+	defer e.warmMu.Unlock()
+
+	if len(e.warm[language]) >= e.warmTarget {
+		proc.kill()
+		return nil
+	}
+	if e.warm == nil {
+		e.warm = make(map[string][]*warmProcess)
+	}
+	e.warm[language] = append(e.warm[language], proc)
+	return nil
+}
+
+// startWarm 启动 language 的解释器，使其阻塞在读取 fd 3 上
+func (e *CodeExecutor) startWarm(language string) (*warmProcess, error) {
+	name, args, _ := e.warmCommand(language)
+	ctx, kill := context.WithCancel(context.Background())
+	cmd := exec.CommandContext(ctx, name, args...)
+	if err := e.prepareUserCommand(cmd, ExecOptions{pythonLib: language == "python3"}); err != nil {
+		kill()
+		return nil, err
+	}
+
+	codeReader, codeWriter, err := os.Pipe()
+	if err != nil {
+		kill()
+		return nil, err
+	}
+	defer codeReader.Close()
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		kill()
+		codeWriter.Close()
+		return nil, err
+	}
+
+	proc := &warmProcess{
+		cmd:    cmd,
+		kill:   kill,
+		code:   codeWriter,
+		stdin:  stdin,
+		stdout: &switchWriter{},
+		stderr: &switchWriter{},
+		done:   make(chan struct{}),
+	}
+	cmd.ExtraFiles = []*os.File{codeReader}
+	cmd.Stdout = proc.stdout
+	cmd.Stderr = proc.stderr
+	stop := setupProcessGroup(cmd, e.killGrace)
+	// 进程组被杀死后，残留的孙进程可能仍持有输出管道，避免 Wait 无限阻塞
+	cmd.WaitDelay = e.killGrace + time.Second
+	if err := cmd.Start(); err != nil {
+		kill()
+		codeWriter.Close()
+		return nil, err
+	}
+
+	go func() {
+		proc.err = cmd.Wait()
+		stop()
+		kill()
+		codeWriter.Close()
+		close(proc.done)
+	}()
+	return proc, nil
+}
+
+// takeWarm 取出一个 language 的空闲预热进程，并在后台补充新的进程。
+// opts 要求预热进程无法满足的执行环境，或没有空闲进程时返回 nil
+func (e *CodeExecutor) takeWarm(language string, opts ExecOptions) *warmProcess {
+	if opts.sourceFile != "" || opts.WorkDir != "" || len(opts.Env) > 0 || opts.InheritEnv || opts.PythonVersion != "" ||
+		len(opts.Args) > 0 || opts.packageEnv != "" || opts.CombineOutput {
+		return nil
+	}
+
+	e.warmMu.Lock()
+	defer e.warmMu.Unlock()
+
+	idle := e.warm[language]
+	for len(idle) > 0 {
+		proc := idle[0]
+		idle = idle[1:]
+		e.warm[language] = idle
+		go e.addWarm(language)
+		// 空闲期间异常退出的进程直接丢弃
+		if !proc.exited() {
+			return proc
+		}
+	}
+	return nil
+}
+
+// runWarm 把代码与标准输入交给预热进程，等待其退出并构造执行结果，ctx 结束时终止进程
+func (e *CodeExecutor) runWarm(ctx context.Context, proc *warmProcess, code string, opts ExecOptions) ExecutionResult {
+	output := e.userOutput(proc.cmd, opts)
+	proc.stdout.set(output.stdoutW)
+	proc.stderr.set(output.stderrW)
+	stop := context.AfterFunc(ctx, proc.kill)
+	defer stop()
+
+	start := time.Now()
+	// 进程可能在读完之前就退出，写入错误由进程的退出状态体现
+	go func() {
+		io.WriteString(proc.code, code)
+		proc.code.Close()
+	}()
+	go func() {
+		io.WriteString(proc.stdin, opts.Stdin)
+		proc.stdin.Close()
+	}()
+	<-proc.done
+
+	result := e.commandResult(proc.cmd, output, proc.err, time.Since(start))
+	return e.annotateLimits(result, proc.cmd.ProcessState)
+}
+
+// stopWarm 撤销 Warmup 并终止所有空闲的预热进程
+func (e *CodeExecutor) stopWarm() {
+	e.warmMu.Lock()
+	e.warmTarget = 0
+	idle := e.warm
+	e.warm = nil
+	e.warmMu.Unlock()
+
+	for _, procs := range idle {
+		for _, proc := range procs {
+			proc.kill()
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package sandbox
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"sort"
+	"strings"
+)
+
+// wasmMagic 是 WebAssembly 二进制模块的文件头
+var wasmMagic = []byte("\x00asm")
+
+// decodeWasm 把 code 解析为 WebAssembly 模块：以 "\0asm" 开头时视为原始字节，
+// 否则按 base64（标准或 URL 编码，允许换行）解码，解码结果同样须以模块头开头
+func decodeWasm(code string) ([]byte, bool) {
+	if strings.HasPrefix(code, string(wasmMagic)) {
+		return []byte(code), true
+	}
+	text := strings.Join(strings.Fields(code), "")
+	for _, enc := range []*base64.Encoding{base64.StdEncoding, base64.URLEncoding, base64.RawStdEncoding, base64.RawURLEncoding} {
+		if module, err := enc.DecodeString(text); err == nil && bytes.HasPrefix(module, wasmMagic) {
+			return module, true
+		}
+	}
+	return nil, false
+}
+
+// runWasmCode 用 wasmtime 执行 WASI 模块，标准输入输出与普通进程相同。
+// 不预打开任何目录，也不授予网络权限，模块只能看到 opts.Env 中的环境变量和 opts.Args
+func (e *CodeExecutor) runWasmCode(ctx context.Context, code string, opts ExecOptions) ExecutionResult {
+	module, ok := decodeWasm(code)
+	if !ok {
+		return errorResultFrom(e.sandboxError(ErrorKindInvalidRequest, MsgInvalidWasm))
+	}
+
+	args := []string{"run"}
+	keys := make([]string, 0, len(opts.Env))
+	for key := range opts.Env {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		args = append(args, "--env", key+"="+opts.Env[key])
+	}
+	return e.runSourceFile(ctx, opts, "wasm-*.wasm", string(module), "wasmtime", append(args, "--")...)
+}
@@ -0,0 +1,57 @@
+package sandbox
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// prepareWorkDir 校验并创建工作目录，返回其绝对路径。
+// dir 为空字符串时表示不指定工作目录，子进程沿用当前进程的工作目录
+func (e *CodeExecutor) prepareWorkDir(dir string) (string, error) {
+	if dir == "" {
+		return "", nil
+	}
+	if strings.TrimSpace(dir) == "" {
+		return "", e.sandboxError(ErrorKindInvalidRequest, MsgWorkDirBlank)
+	}
+
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", e.sandboxError(ErrorKindInvalidRequest, MsgWorkDirUnusable, err)
+	}
+	if err := os.MkdirAll(abs, 0o755); err != nil {
+		return "", e.sandboxError(ErrorKindInternal, MsgWorkDirUnusable, err)
+	}
+	info, err := os.Stat(abs)
+	if err != nil {
+		return "", e.sandboxError(ErrorKindInternal, MsgWorkDirUnusable, err)
+	}
+	if !info.IsDir() {
+		return "", e.sandboxError(ErrorKindInvalidRequest, MsgWorkDirNotDir, abs)
+	}
+	return abs, nil
+}
+
+// validateRelativePath 校验 name 是不会逃逸出工作目录的相对路径，拒绝 ../x、绝对路径等
+func (e *CodeExecutor) validateRelativePath(name string) error {
+	if !filepath.IsLocal(name) {
+		return e.sandboxError(ErrorKindInvalidRequest, MsgInvalidFileName, name)
+	}
+	return nil
+}
+
+// writeWorkspaceFile 将 content 写入 dir 下的相对路径 name，按需创建中间目录
+func (e *CodeExecutor) writeWorkspaceFile(dir string, name string, content []byte) error {
+	if err := e.validateRelativePath(name); err != nil {
+		return err
+	}
+	path := filepath.Join(dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return e.sandboxError(ErrorKindInternal, MsgWriteFile, name, err)
+	}
+	if err := os.WriteFile(path, content, 0o644); err != nil {
+		return e.sandboxError(ErrorKindInternal, MsgWriteFile, name, err)
+	}
+	return nil
+}